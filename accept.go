@@ -0,0 +1,62 @@
+package headers
+
+// destinationAccept is an entry of the per-destination table
+type destinationAccept struct {
+	accept   Accept
+	encoding AcceptEncoding // Empty when the profile's usual Accept-Encoding applies
+}
+
+// chromeAccept is shared by Chrome and Edge
+var chromeAccept = map[SecFetchDest]destinationAccept{
+	SecFetchDestDocument: {accept: AcceptChromeDocument},
+	SecFetchDestIFrame:   {accept: AcceptChromeDocument},
+	SecFetchDestFrame:    {accept: AcceptChromeDocument},
+	SecFetchDestImage:    {accept: AcceptChromeImage},
+	SecFetchDestStyle:    {accept: AcceptStyle},
+	SecFetchDestAudio:    {accept: AcceptAll, encoding: AcceptEncodingChromeMedia},
+	SecFetchDestVideo:    {accept: AcceptAll, encoding: AcceptEncodingChromeMedia},
+}
+
+// destinationAccepts holds the values each profile sends per destination;
+// destinations missing from a profile's table use */*
+var destinationAccepts = map[Profile]map[SecFetchDest]destinationAccept{
+	ProfileChrome: chromeAccept,
+	ProfileEdge:   chromeAccept,
+	ProfileFirefox: {
+		SecFetchDestDocument: {accept: AcceptFirefoxDocument},
+		SecFetchDestIFrame:   {accept: AcceptFirefoxDocument},
+		SecFetchDestFrame:    {accept: AcceptFirefoxDocument},
+		SecFetchDestImage:    {accept: AcceptFirefoxImage},
+		SecFetchDestStyle:    {accept: AcceptStyle},
+		SecFetchDestFont:     {accept: AcceptFirefoxFont},
+		SecFetchDestAudio:    {accept: AcceptFirefoxAudio, encoding: AcceptEncodingMedia},
+		SecFetchDestVideo:    {accept: AcceptFirefoxVideo, encoding: AcceptEncodingMedia},
+	},
+	ProfileSafari: {
+		SecFetchDestDocument: {accept: AcceptSafariDocument},
+		SecFetchDestIFrame:   {accept: AcceptSafariDocument},
+		SecFetchDestFrame:    {accept: AcceptSafariDocument},
+		SecFetchDestImage:    {accept: AcceptSafariImage},
+		SecFetchDestStyle:    {accept: AcceptStyle},
+		SecFetchDestAudio:    {accept: AcceptAll, encoding: AcceptEncodingMedia},
+		SecFetchDestVideo:    {accept: AcceptAll, encoding: AcceptEncodingMedia},
+	},
+}
+
+// DestinationAccept returns the Accept value a browser profile sends for a
+// request destination, and the Accept-Encoding value when it differs from the
+// profile's usual one (as it does for media). Unknown profiles use the Chrome
+// table. WebSocket handshakes carry no Accept, so both values are empty.
+func DestinationAccept(profile Profile, dest SecFetchDest) (Accept, AcceptEncoding) {
+	if dest == SecFetchDestWebSocket {
+		return "", ""
+	}
+	table, ok := destinationAccepts[profile]
+	if !ok {
+		table = chromeAccept
+	}
+	if entry, ok := table[dest]; ok {
+		return entry.accept, entry.encoding
+	}
+	return AcceptAll, ""
+}
@@ -0,0 +1,457 @@
+package headers
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache defaults
+const (
+	HeuristicFractionDefault = 0.1
+	HeuristicMaxDefault      = 24 * time.Hour
+
+	// HeaderCacheStatus reports how the cache handled a response (RFC 9211)
+	HeaderCacheStatus = "Cache-Status"
+	cacheStatusName   = "headers"
+)
+
+// CacheTransport is an http.RoundTripper implementing a private HTTP cache as
+// defined by RFC 9111. It serves fresh responses from its store, honors request
+// and response Cache-Control directives (including stale-while-revalidate,
+// stale-if-error and immutable), computes Age, selects variants using Vary, and
+// revalidates stale responses with conditional requests.
+type CacheTransport struct {
+	Transport http.RoundTripper // Underlying transport, http.DefaultTransport when nil
+	Store     CacheStore
+	BodyLimit int64 // Bodies larger than this are not stored, CacheBodyLimitDefault when zero
+
+	// Heuristic freshness for responses with Last-Modified but no explicit
+	// expiration: HeuristicFraction of the time since modification, capped at HeuristicMax
+	HeuristicFraction float64
+	HeuristicMax      time.Duration
+
+	revalidating sync.Map // Primary keys with a background revalidation in flight
+}
+
+// NewCacheTransport creates a CacheTransport; a nil store falls back to an
+// unbounded in-memory LRU store
+func NewCacheTransport(next http.RoundTripper, store CacheStore) *CacheTransport {
+	if store == nil {
+		store = NewLRUCacheStore(0)
+	}
+	return &CacheTransport{
+		Transport:         next,
+		Store:             store,
+		HeuristicFraction: HeuristicFractionDefault,
+		HeuristicMax:      HeuristicMaxDefault,
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *CacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isSafeMethod(req.Method) {
+		resp, err := t.next().RoundTrip(req)
+		if err == nil && resp.StatusCode < http.StatusBadRequest {
+			t.invalidate(req, resp)
+		}
+		return resp, err
+	}
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return t.next().RoundTrip(req)
+	}
+
+	reqCC := requestCacheDirectives(req.Header)
+	entry := lookupCache(t.Store, req)
+	if entry == nil {
+		if reqCC.Has("only-if-cached") {
+			return gatewayTimeout(req), nil
+		}
+		return t.fetch(req, reqCC, "fwd=miss")
+	}
+
+	now := time.Now()
+	respCC := ParseCacheControl(entry.Header.Values("Cache-Control")...)
+	age := cacheAge(entry, now)
+	lifetime := t.freshnessLifetime(entry, respCC)
+
+	switch t.usability(reqCC, respCC, age, lifetime) {
+	case cacheFresh:
+		return serveCached(entry, req, age, "hit"), nil
+	case cacheStaleAllowed:
+		return serveCached(entry, req, age, "hit; detail=stale"), nil
+	case cacheStaleRevalidate:
+		t.revalidateInBackground(req, entry)
+		return serveCached(entry, req, age, "hit; detail=stale-while-revalidate"), nil
+	}
+
+	if reqCC.Has("only-if-cached") {
+		return gatewayTimeout(req), nil
+	}
+	return t.revalidate(req, entry, reqCC, respCC, age, lifetime)
+}
+
+// cacheUsability is the outcome of checking a stored response against a request
+type cacheUsability int
+
+const (
+	cacheRevalidate cacheUsability = iota
+	cacheFresh
+	cacheStaleAllowed
+	cacheStaleRevalidate
+)
+
+// usability decides whether a stored response may be served without contacting
+// the origin (RFC 9111 section 4)
+func (t *CacheTransport) usability(reqCC, respCC CacheDirectives, age, lifetime time.Duration) cacheUsability {
+	if respCC.Has("no-cache") {
+		return cacheRevalidate
+	}
+
+	fresh := age < lifetime
+	immutable := fresh && respCC.Has("immutable")
+
+	// Reload-style request directives, which immutable responses ignore while fresh
+	if !immutable {
+		if reqCC.Has("no-cache") {
+			return cacheRevalidate
+		}
+		if maxAge, ok := reqCC.Seconds("max-age"); ok && age > maxAge {
+			return cacheRevalidate
+		}
+	}
+	if minFresh, ok := reqCC.Seconds("min-fresh"); ok && lifetime-age < minFresh {
+		return cacheRevalidate
+	}
+	if fresh {
+		return cacheFresh
+	}
+
+	staleness := age - lifetime
+	if respCC.Has("must-revalidate") || respCC.Has("proxy-revalidate") {
+		return cacheRevalidate
+	}
+	if reqCC.Has("max-stale") {
+		maxStale, ok := reqCC.Seconds("max-stale")
+		if !ok || staleness <= maxStale {
+			return cacheStaleAllowed
+		}
+	}
+	if swr, ok := respCC.Seconds("stale-while-revalidate"); ok && staleness <= swr {
+		return cacheStaleRevalidate
+	}
+	return cacheRevalidate
+}
+
+// revalidate sends a conditional request for a stored response and serves the
+// stored body on 304, or a stale response when stale-if-error allows it
+func (t *CacheTransport) revalidate(req *http.Request, entry *CacheEntry, reqCC, respCC CacheDirectives, age, lifetime time.Duration) (*http.Response, error) {
+	outReq := req
+	if entry.HasValidators() && !hasConditionalHeaders(req.Header) {
+		outReq = conditionalRequest(req, entry)
+	}
+
+	requestTime := time.Now()
+	resp, err := t.next().RoundTrip(outReq)
+	responseTime := time.Now()
+
+	if err != nil || isServerError(resp.StatusCode) {
+		if t.staleIfError(reqCC, respCC, age-lifetime) {
+			if resp != nil {
+				drainBody(resp.Body)
+			}
+			return serveCached(entry, req, cacheAge(entry, responseTime), "hit; detail=stale-if-error"), nil
+		}
+		if err != nil && (respCC.Has("must-revalidate") || respCC.Has("no-cache")) {
+			return gatewayTimeout(req), nil
+		}
+		return resp, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && outReq != req {
+		drainBody(resp.Body)
+		updated := entry.withUpdatedHeaders(resp.Header, requestTime, responseTime)
+		if !reqCC.Has("no-store") {
+			storeCache(t.Store, req, updated)
+		}
+		return serveCached(updated, req, cacheAge(updated, time.Now()), "fwd=stale; fwd-status=304"), nil
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		// The 304 answers the client's own validators: refresh the stored
+		// response when it is the one validated, and pass the 304 through
+		if !reqCC.Has("no-store") && validatesEntry(resp.Header, entry) {
+			storeCache(t.Store, req, entry.withUpdatedHeaders(resp.Header, requestTime, responseTime))
+		}
+		resp.Header.Set(HeaderCacheStatus, cacheStatusName+"; fwd=stale; fwd-status=304")
+		return resp, nil
+	}
+
+	return t.store(req, resp, reqCC, requestTime, responseTime, "fwd=stale")
+}
+
+// staleIfError reports whether a stale response may be served after an error
+func (t *CacheTransport) staleIfError(reqCC, respCC CacheDirectives, staleness time.Duration) bool {
+	if respCC.Has("must-revalidate") || respCC.Has("proxy-revalidate") || respCC.Has("no-cache") {
+		return false
+	}
+	for _, cc := range []CacheDirectives{reqCC, respCC} {
+		if limit, ok := cc.Seconds("stale-if-error"); ok && staleness <= limit {
+			return true
+		}
+	}
+	return false
+}
+
+// revalidateInBackground refreshes a stored response without blocking the caller
+func (t *CacheTransport) revalidateInBackground(req *http.Request, entry *CacheEntry) {
+	key := cacheKey(req)
+	if _, busy := t.revalidating.LoadOrStore(key, struct{}{}); busy {
+		return
+	}
+
+	bgReq := req.Clone(context.WithoutCancel(req.Context()))
+	go func() {
+		defer t.revalidating.Delete(key)
+
+		resp, err := t.revalidate(bgReq, entry, make(CacheDirectives), make(CacheDirectives), 0, 0)
+		if err == nil {
+			drainBody(resp.Body)
+		}
+	}()
+}
+
+// fetch forwards a request the cache cannot answer and stores the response
+func (t *CacheTransport) fetch(req *http.Request, reqCC CacheDirectives, status string) (*http.Response, error) {
+	requestTime := time.Now()
+	resp, err := t.next().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	return t.store(req, resp, reqCC, requestTime, time.Now(), status)
+}
+
+// store keeps a response when RFC 9111 section 3 allows it
+func (t *CacheTransport) store(req *http.Request, resp *http.Response, reqCC CacheDirectives, requestTime, responseTime time.Time, status string) (*http.Response, error) {
+	if !reqCC.Has("no-store") && t.isCacheable(resp) {
+		var err error
+		if resp, err = bufferAndStore(t.Store, req, resp, t.BodyLimit, requestTime, responseTime); err != nil {
+			return nil, err
+		}
+	}
+	resp.Header.Set(HeaderCacheStatus, cacheStatusName+"; "+status)
+	return resp, nil
+}
+
+// isCacheable reports whether a response to a GET may be stored
+func (t *CacheTransport) isCacheable(resp *http.Response) bool {
+	switch {
+	case resp.StatusCode < http.StatusOK, resp.StatusCode == http.StatusPartialContent, resp.StatusCode == http.StatusNotModified:
+		// 304 only updates a stored response (RFC 9111 section 4.3.4)
+		return false
+	case !isStorableResponse(resp.Header):
+		return false
+	}
+
+	cc := ParseCacheControl(resp.Header.Values("Cache-Control")...)
+	explicit := cc.Has("max-age") || cc.Has("public") || cc.Has("no-cache") || resp.Header.Get("Expires") != ""
+	if explicit {
+		return true
+	}
+	if !isHeuristicallyCacheable(resp.StatusCode) {
+		return false
+	}
+	return (&CacheEntry{Header: resp.Header}).HasValidators()
+}
+
+// freshnessLifetime computes how long a stored response stays fresh (RFC 9111
+// section 4.2.1); s-maxage is ignored because this is a private cache
+func (t *CacheTransport) freshnessLifetime(entry *CacheEntry, cc CacheDirectives) time.Duration {
+	if maxAge, ok := cc.Seconds("max-age"); ok {
+		return maxAge
+	}
+	if cc.Has("max-age") {
+		// Invalid max-age values make the response stale
+		return 0
+	}
+
+	date := entryDate(entry)
+	if expires := entry.Header.Get("Expires"); expires != "" {
+		t, err := ParseHTTPDate(expires)
+		if err != nil {
+			return 0
+		}
+		return max(t.Sub(date), 0)
+	}
+
+	if !isHeuristicallyCacheable(entry.StatusCode) && !cc.Has("public") {
+		return 0
+	}
+	lastModified, err := ParseHTTPDate(entry.LastModified())
+	if err != nil || !lastModified.Before(date) {
+		return 0
+	}
+
+	fraction := t.HeuristicFraction
+	if fraction <= 0 {
+		fraction = HeuristicFractionDefault
+	}
+	limit := t.HeuristicMax
+	if limit <= 0 {
+		limit = HeuristicMaxDefault
+	}
+	return min(time.Duration(float64(date.Sub(lastModified))*fraction), limit)
+}
+
+// invalidate drops stored responses affected by a successful unsafe request,
+// including same-origin Location and Content-Location targets
+func (t *CacheTransport) invalidate(req *http.Request, resp *http.Response) {
+	invalidateCache(t.Store, req)
+	for _, name := range []string{"Location", "Content-Location"} {
+		value := resp.Header.Get(name)
+		if value == "" {
+			continue
+		}
+		target, err := req.URL.Parse(value)
+		if err != nil || target.Scheme != req.URL.Scheme || target.Host != req.URL.Host {
+			continue
+		}
+		other := *req
+		other.URL = target
+		invalidateCache(t.Store, &other)
+	}
+}
+
+func (t *CacheTransport) next() http.RoundTripper {
+	if t.Transport == nil {
+		return http.DefaultTransport
+	}
+	return t.Transport
+}
+
+// cacheAge computes the current age of a stored response (RFC 9111 section 4.2.3)
+func cacheAge(entry *CacheEntry, now time.Time) time.Duration {
+	apparentAge := max(entry.ResponseTime.Sub(entryDate(entry)), 0)
+
+	var ageValue time.Duration
+	if n, err := strconv.ParseInt(strings.TrimSpace(entry.Header.Get("Age")), 10, 64); err == nil && n > 0 {
+		ageValue = time.Duration(n) * time.Second
+	}
+	responseDelay := entry.ResponseTime.Sub(entry.RequestTime)
+	correctedInitialAge := max(apparentAge, ageValue+responseDelay)
+
+	return correctedInitialAge + now.Sub(entry.ResponseTime)
+}
+
+// entryDate returns the Date of a stored response, falling back to when it was received
+func entryDate(entry *CacheEntry) time.Time {
+	if date, err := ParseHTTPDate(entry.Header.Get("Date")); err == nil {
+		return date
+	}
+	return entry.ResponseTime
+}
+
+// serveCached builds a response from the store with Age and Cache-Status set
+func serveCached(entry *CacheEntry, req *http.Request, age time.Duration, status string) *http.Response {
+	resp := entry.Response(req)
+	if entry.StatusCode == http.StatusOK && notModified(entry, req.Header) {
+		resp = notModifiedResponse(entry, req)
+	}
+	resp.Header.Set("Age", strconv.FormatInt(int64(age/time.Second), 10))
+	resp.Header.Set(HeaderCacheStatus, cacheStatusName+"; "+status)
+	return resp
+}
+
+// validatesEntry reports whether a 304 response selects the stored response:
+// its ETag matches the stored one or, without ETags, so does Last-Modified
+// (RFC 9111 section 4.3.4)
+func validatesEntry(header http.Header, entry *CacheEntry) bool {
+	if value := header.Get("ETag"); value != "" {
+		tag, err := ParseETag(value)
+		stored, ok := entry.ETag()
+		return err == nil && ok && tag.String() == stored.String()
+	}
+	if _, ok := entry.ETag(); ok {
+		return false
+	}
+	lastModified := header.Get("Last-Modified")
+	return lastModified != "" && lastModified == entry.LastModified()
+}
+
+// notModified evaluates the client's own If-None-Match or If-Modified-Since
+// against a stored response (RFC 9111 section 4.3.2). If-Modified-Since is
+// ignored when If-None-Match is present.
+func notModified(entry *CacheEntry, header http.Header) bool {
+	if value := header.Get("If-None-Match"); value != "" {
+		list, err := ParseETagList(value)
+		if err != nil {
+			return false
+		}
+		if list.Any {
+			return true
+		}
+		tag, ok := entry.ETag()
+		return ok && list.MatchIfNoneMatch(tag)
+	}
+
+	value := header.Get("If-Modified-Since")
+	if value == "" {
+		return false
+	}
+	since, err := ParseHTTPDate(value)
+	if err != nil {
+		return false
+	}
+	lastModified, err := ParseHTTPDate(entry.LastModified())
+	if err != nil {
+		lastModified = entryDate(entry)
+	}
+	return !lastModified.IsZero() && !lastModified.After(since)
+}
+
+// notModifiedResponse is a 304 carrying the stored validators and the header
+// fields RFC 9110 section 15.4.5 requires
+func notModifiedResponse(entry *CacheEntry, req *http.Request) *http.Response {
+	header := make(http.Header)
+	for _, name := range []string{"Cache-Control", "Content-Location", "Date", "ETag", "Expires", "Last-Modified", "Vary"} {
+		if values := entry.Header.Values(name); len(values) > 0 {
+			header[http.CanonicalHeaderKey(name)] = slices.Clone(values)
+		}
+	}
+	resp := (&CacheEntry{StatusCode: http.StatusNotModified, Header: header}).Response(req)
+	resp.Header.Del("Content-Length")
+	return resp
+}
+
+// gatewayTimeout is the response for requests the cache must answer but cannot
+func gatewayTimeout(req *http.Request) *http.Response {
+	return (&CacheEntry{
+		StatusCode: http.StatusGatewayTimeout,
+		Header:     http.Header{HeaderCacheStatus: {cacheStatusName + "; fwd=miss"}},
+	}).Response(req)
+}
+
+// isHeuristicallyCacheable reports whether a status code is cacheable by default
+// (RFC 9110 section 15.1)
+func isHeuristicallyCacheable(status int) bool {
+	switch status {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusNoContent,
+		http.StatusMultipleChoices, http.StatusMovedPermanently, http.StatusPermanentRedirect,
+		http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusGone,
+		http.StatusRequestURITooLong, http.StatusNotImplemented:
+		return true
+	}
+	return false
+}
+
+// isServerError reports whether stale-if-error applies to a status code
+func isServerError(status int) bool {
+	switch status {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
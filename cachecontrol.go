@@ -0,0 +1,122 @@
+package headers
+
+import (
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CacheDirectives holds parsed Cache-Control directives keyed by lowercase
+// name; directives without an argument map to ""
+type CacheDirectives map[string]string
+
+// ParseCacheControl parses one or more Cache-Control header values. Later
+// occurrences of a directive do not override earlier ones.
+func ParseCacheControl(values ...string) CacheDirectives {
+	directives := make(CacheDirectives)
+	for _, value := range values {
+		for _, part := range splitQuotedList(value, ',') {
+			name, arg, _ := strings.Cut(part, "=")
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			if _, seen := directives[name]; !seen {
+				directives[name] = unquote(strings.TrimSpace(arg))
+			}
+		}
+	}
+	return directives
+}
+
+// Has reports whether the directive is present
+func (d CacheDirectives) Has(name string) bool {
+	_, ok := d[name]
+	return ok
+}
+
+// Seconds returns the delta-seconds argument of a directive. Values too large
+// to represent are clamped, and invalid values report false.
+func (d CacheDirectives) Seconds(name string) (time.Duration, bool) {
+	arg, ok := d[name]
+	if !ok || arg == "" || strings.TrimLeft(arg, "0123456789") != "" {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || n > math.MaxInt64/int64(time.Second) {
+		return time.Duration(math.MaxInt64), true
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+// String formats the directives as a Cache-Control header value in sorted order
+func (d CacheDirectives) String() string {
+	names := make([]string, 0, len(d))
+	for name := range d {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		if arg := d[name]; arg != "" {
+			parts = append(parts, name+"="+quoteIfNeeded(arg))
+		} else {
+			parts = append(parts, name)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// JoinCacheControl combines several Cache-Control directives into one value
+func JoinCacheControl(directives ...CacheControl) CacheControl {
+	parts := make([]string, 0, len(directives))
+	for _, directive := range directives {
+		if directive != "" {
+			parts = append(parts, string(directive))
+		}
+	}
+	return CacheControl(strings.Join(parts, ", "))
+}
+
+// CacheControlMaxAge returns a max-age directive for the given duration
+func CacheControlMaxAge(d time.Duration) CacheControl {
+	return CacheControl("max-age=" + strconv.FormatInt(int64(d/time.Second), 10))
+}
+
+// CacheControlMaxStale returns a max-stale request directive; a non-positive
+// duration accepts responses stale by any amount
+func CacheControlMaxStale(d time.Duration) CacheControl {
+	if d <= 0 {
+		return CacheControlMaxStaleAny
+	}
+	return CacheControl("max-stale=" + strconv.FormatInt(int64(d/time.Second), 10))
+}
+
+// CacheControlMinFresh returns a min-fresh request directive
+func CacheControlMinFresh(d time.Duration) CacheControl {
+	return CacheControl("min-fresh=" + strconv.FormatInt(int64(d/time.Second), 10))
+}
+
+// CacheControlStaleIfError returns a stale-if-error response directive
+func CacheControlStaleIfError(d time.Duration) CacheControl {
+	return CacheControl("stale-if-error=" + strconv.FormatInt(int64(d/time.Second), 10))
+}
+
+// requestCacheDirectives returns the request directives, honoring Pragma:
+// no-cache when no Cache-Control header is present
+func requestCacheDirectives(h http.Header) CacheDirectives {
+	if values := h.Values("Cache-Control"); len(values) > 0 {
+		return ParseCacheControl(values...)
+	}
+	directives := make(CacheDirectives)
+	for _, value := range h.Values("Pragma") {
+		if ParseCacheControl(value).Has("no-cache") {
+			directives["no-cache"] = ""
+		}
+	}
+	return directives
+}
@@ -0,0 +1,239 @@
+package headers
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheEntry is a stored response together with the request headers it varies on
+type CacheEntry struct {
+	StatusCode   int
+	Header       http.Header
+	Body         []byte
+	Vary         []string  // Canonical request header names from the Vary response header
+	RequestTime  time.Time // When the request that produced the response was sent
+	ResponseTime time.Time // When the response was received
+}
+
+// CacheStore is the storage used by the caching transports. Implementations must
+// be safe for concurrent use and must not modify entries after handing them out.
+type CacheStore interface {
+	Get(key string) (*CacheEntry, bool)
+	Set(key string, entry *CacheEntry)
+	Delete(key string)
+}
+
+// ETag returns the parsed ETag of the stored response, if any
+func (e *CacheEntry) ETag() (ETag, bool) {
+	tag, err := ParseETag(e.Header.Get("ETag"))
+	return tag, err == nil
+}
+
+// LastModified returns the Last-Modified value of the stored response, if any
+func (e *CacheEntry) LastModified() string {
+	return e.Header.Get("Last-Modified")
+}
+
+// HasValidators reports whether the entry can be revalidated with a conditional request
+func (e *CacheEntry) HasValidators() bool {
+	_, ok := e.ETag()
+	return ok || e.LastModified() != ""
+}
+
+// Response rebuilds an *http.Response from the stored entry for req
+func (e *CacheEntry) Response(req *http.Request) *http.Response {
+	header := e.Header.Clone()
+	header.Set("Content-Length", strconv.Itoa(len(e.Body)))
+
+	return &http.Response{
+		Status:        strconv.Itoa(e.StatusCode) + " " + http.StatusText(e.StatusCode),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// size approximates the memory held by the entry
+func (e *CacheEntry) size() int64 {
+	n := int64(len(e.Body))
+	for key, values := range e.Header {
+		n += int64(len(key))
+		for _, v := range values {
+			n += int64(len(v))
+		}
+	}
+	return n
+}
+
+// withUpdatedHeaders returns a copy of the entry with header fields from a 304
+// response applied, as required when freshening a stored response
+func (e *CacheEntry) withUpdatedHeaders(h http.Header, requestTime, responseTime time.Time) *CacheEntry {
+	updated := *e
+	updated.Header = e.Header.Clone()
+	for key, values := range h {
+		switch key {
+		case "Content-Length", "Content-Encoding", "Content-Range", "Transfer-Encoding":
+			continue
+		}
+		updated.Header[key] = slices.Clone(values)
+	}
+	updated.RequestTime = requestTime
+	updated.ResponseTime = responseTime
+	return &updated
+}
+
+// cacheKey returns the primary cache key of a request
+func cacheKey(req *http.Request) string {
+	u := *req.URL
+	u.Fragment = ""
+	return u.String()
+}
+
+// varyKey returns the secondary cache key selecting a variant for req
+func varyKey(primary string, vary []string, req *http.Request) string {
+	var sb strings.Builder
+	sb.WriteString(primary)
+	for _, name := range vary {
+		sb.WriteString("\x00")
+		sb.WriteString(name)
+		sb.WriteString("=")
+		sb.WriteString(normalizeVaryValue(req.Header.Values(name)))
+	}
+	return sb.String()
+}
+
+// normalizeVaryValue folds whitespace differences in request header values
+func normalizeVaryValue(values []string) string {
+	var parts []string
+	for _, value := range values {
+		for part := range strings.SplitSeq(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				parts = append(parts, part)
+			}
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// parseVary returns the sorted canonical header names listed in Vary and
+// whether the response varies on "*", which makes it unusable from cache
+func parseVary(h http.Header) (names []string, star bool) {
+	for _, value := range h.Values("Vary") {
+		for name := range strings.SplitSeq(value, ",") {
+			name = strings.TrimSpace(name)
+			switch name {
+			case "":
+			case "*":
+				return nil, true
+			default:
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names), false
+}
+
+// LRUCacheStore is an in-memory CacheStore that evicts the least recently used
+// entries once the total size exceeds its byte limit
+type LRUCacheStore struct {
+	mu       sync.Mutex
+	maxBytes int64
+	used     int64
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+// lruItem is a value kept in the LRU list
+type lruItem struct {
+	key   string
+	entry *CacheEntry
+	size  int64
+}
+
+// NewLRUCacheStore creates an in-memory store holding up to maxBytes of entries;
+// a non-positive limit means unbounded
+func NewLRUCacheStore(maxBytes int64) *LRUCacheStore {
+	return &LRUCacheStore{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns the entry stored under key and marks it as recently used
+func (s *LRUCacheStore) Get(key string) (*CacheEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	s.order.MoveToFront(elem)
+	return elem.Value.(*lruItem).entry, true
+}
+
+// Set stores entry under key, evicting old entries as needed
+func (s *LRUCacheStore) Set(key string, entry *CacheEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	size := entry.size() + int64(len(key))
+	if s.maxBytes > 0 && size > s.maxBytes {
+		s.remove(key)
+		return
+	}
+
+	if elem, ok := s.items[key]; ok {
+		item := elem.Value.(*lruItem)
+		s.used += size - item.size
+		item.entry, item.size = entry, size
+		s.order.MoveToFront(elem)
+	} else {
+		s.items[key] = s.order.PushFront(&lruItem{key: key, entry: entry, size: size})
+		s.used += size
+	}
+
+	for s.maxBytes > 0 && s.used > s.maxBytes {
+		s.remove(s.order.Back().Value.(*lruItem).key)
+	}
+}
+
+// Delete removes the entry stored under key
+func (s *LRUCacheStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(key)
+}
+
+// Len returns the number of stored entries
+func (s *LRUCacheStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.order.Len()
+}
+
+func (s *LRUCacheStore) remove(key string) {
+	elem, ok := s.items[key]
+	if !ok {
+		return
+	}
+	s.used -= elem.Value.(*lruItem).size
+	s.order.Remove(elem)
+	delete(s.items, key)
+}
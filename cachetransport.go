@@ -0,0 +1,205 @@
+package headers
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+)
+
+// CacheBodyLimitDefault is the largest response body the caching transports store
+const CacheBodyLimitDefault int64 = 10 << 20
+
+// ConditionalTransport is an http.RoundTripper that remembers validators
+// (ETag, Last-Modified) and bodies of GET responses, revalidates them with
+// If-None-Match and If-Modified-Since on repeat requests, and turns a 304 Not
+// Modified into the cached 200 response. Stored responses are always revalidated.
+type ConditionalTransport struct {
+	Transport http.RoundTripper // Underlying transport, http.DefaultTransport when nil
+	Store     CacheStore
+	BodyLimit int64 // Bodies larger than this are not stored, CacheBodyLimitDefault when zero
+}
+
+// NewConditionalTransport creates a ConditionalTransport; a nil store falls back
+// to an unbounded in-memory LRU store
+func NewConditionalTransport(next http.RoundTripper, store CacheStore) *ConditionalTransport {
+	if store == nil {
+		store = NewLRUCacheStore(0)
+	}
+	return &ConditionalTransport{
+		Transport: next,
+		Store:     store,
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *ConditionalTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	if !isSafeMethod(req.Method) {
+		resp, err := next.RoundTrip(req)
+		if err == nil && resp.StatusCode < http.StatusBadRequest {
+			invalidateCache(t.Store, req)
+		}
+		return resp, err
+	}
+
+	// Requests the caller already made conditional, or ranged, pass straight through
+	if req.Method != http.MethodGet || hasConditionalHeaders(req.Header) || req.Header.Get("Range") != "" {
+		return next.RoundTrip(req)
+	}
+
+	entry := lookupCache(t.Store, req)
+	outReq := req
+	if entry != nil && entry.HasValidators() {
+		outReq = conditionalRequest(req, entry)
+	}
+
+	requestTime := time.Now()
+	resp, err := next.RoundTrip(outReq)
+	if err != nil {
+		return nil, err
+	}
+	responseTime := time.Now()
+
+	if resp.StatusCode == http.StatusNotModified && outReq != req {
+		drainBody(resp.Body)
+		updated := entry.withUpdatedHeaders(resp.Header, requestTime, responseTime)
+		storeCache(t.Store, req, updated)
+		return updated.Response(req), nil
+	}
+
+	// Only responses that can be revalidated later are worth keeping
+	if resp.StatusCode != http.StatusOK || !isStorableResponse(resp.Header) ||
+		!(&CacheEntry{Header: resp.Header}).HasValidators() {
+		return resp, nil
+	}
+
+	return bufferAndStore(t.Store, req, resp, t.BodyLimit, requestTime, responseTime)
+}
+
+// conditionalRequest clones req adding the validators of the stored entry
+func conditionalRequest(req *http.Request, entry *CacheEntry) *http.Request {
+	outReq := req.Clone(req.Context())
+	if tag, ok := entry.ETag(); ok {
+		outReq.Header.Set("If-None-Match", tag.String())
+	}
+	if lm := entry.LastModified(); lm != "" {
+		outReq.Header.Set("If-Modified-Since", lm)
+	}
+	return outReq
+}
+
+// bufferAndStore reads the response body and stores it, unless it exceeds the
+// limit, in which case the response is handed back unbuffered
+func bufferAndStore(store CacheStore, req *http.Request, resp *http.Response, limit int64, requestTime, responseTime time.Time) (*http.Response, error) {
+	if limit <= 0 {
+		limit = CacheBodyLimitDefault
+	}
+	if resp.ContentLength > limit {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	vary, _ := parseVary(resp.Header)
+	storeCache(store, req, &CacheEntry{
+		StatusCode:   resp.StatusCode,
+		Header:       resp.Header.Clone(),
+		Body:         body,
+		Vary:         vary,
+		RequestTime:  requestTime,
+		ResponseTime: responseTime,
+	})
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	return resp, nil
+}
+
+// lookupCache returns the stored entry selected for req, following the Vary
+// index stored under the primary key
+func lookupCache(store CacheStore, req *http.Request) *CacheEntry {
+	key := cacheKey(req)
+	entry, ok := store.Get(key)
+	if !ok {
+		return nil
+	}
+	if len(entry.Vary) == 0 {
+		return entry
+	}
+
+	if entry, ok = store.Get(varyKey(key, entry.Vary, req)); !ok {
+		return nil
+	}
+	return entry
+}
+
+// storeCache stores entry for req. Responses with Vary are stored under a
+// secondary key, with the primary key holding only the list of varying headers.
+func storeCache(store CacheStore, req *http.Request, entry *CacheEntry) {
+	key := cacheKey(req)
+	if len(entry.Vary) == 0 {
+		store.Set(key, entry)
+		return
+	}
+	store.Set(key, &CacheEntry{Vary: entry.Vary})
+	store.Set(varyKey(key, entry.Vary, req), entry)
+}
+
+// invalidateCache drops the stored responses for the target of an unsafe request
+func invalidateCache(store CacheStore, req *http.Request) {
+	key := cacheKey(req)
+	if entry, ok := store.Get(key); ok && len(entry.Vary) > 0 {
+		store.Delete(varyKey(key, entry.Vary, req))
+	}
+	store.Delete(key)
+}
+
+// isSafeMethod reports whether the method is safe per RFC 9110 section 9.2.1
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, "":
+		return true
+	}
+	return false
+}
+
+// hasConditionalHeaders reports whether the request carries any precondition
+func hasConditionalHeaders(h http.Header) bool {
+	for _, name := range []string{"If-None-Match", "If-Modified-Since", "If-Match", "If-Unmodified-Since", "If-Range"} {
+		if h.Get(name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// isStorableResponse rejects responses that forbid storage or vary on everything
+func isStorableResponse(h http.Header) bool {
+	if _, star := parseVary(h); star {
+		return false
+	}
+	return !ParseCacheControl(h.Values("Cache-Control")...).Has("no-store")
+}
+
+// drainBody discards a small remaining body so the connection can be reused
+func drainBody(body io.ReadCloser) {
+	io.CopyN(io.Discard, body, 4<<10)
+	body.Close()
+}
@@ -0,0 +1,207 @@
+package headers
+
+import (
+	"iter"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Headers is an ordered header collection with case-insensitive names and
+// multiple values per name, for headers such as Set-Cookie, Link, Via and Vary
+// that cannot be held in a map[string]string. Names keep the spelling and
+// position of their first use. The zero value is empty and ready to use, and
+// a nil *Headers reads as empty.
+type Headers struct {
+	fields []headerField
+}
+
+// headerField is one header name with its values in order
+type headerField struct {
+	name   string
+	values []string
+}
+
+// NewHeaders creates a Headers collection from name/value pairs
+func NewHeaders(pairs ...string) *Headers {
+	h := &Headers{}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		h.Add(pairs[i], pairs[i+1])
+	}
+	return h
+}
+
+// HeadersFromHTTP converts an http.Header, ordering names alphabetically since
+// the map has no order
+func HeadersFromHTTP(header http.Header) *Headers {
+	h := &Headers{}
+	for _, name := range slices.Sorted(maps.Keys(header)) {
+		if len(header[name]) == 0 {
+			continue
+		}
+		h.fields = append(h.fields, headerField{name: name, values: slices.Clone(header[name])})
+	}
+	return h
+}
+
+// index returns the position of a name, or -1
+func (h *Headers) index(name string) int {
+	if h == nil {
+		return -1
+	}
+	// Header sets are small, so a linear scan beats maintaining an index
+	for i, field := range h.fields {
+		if strings.EqualFold(field.name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Get returns the first value of a header, or ""
+func (h *Headers) Get(name string) string {
+	if i := h.index(name); i >= 0 && len(h.fields[i].values) > 0 {
+		return h.fields[i].values[0]
+	}
+	return ""
+}
+
+// Values returns all values of a header in order
+func (h *Headers) Values(name string) []string {
+	if i := h.index(name); i >= 0 {
+		return slices.Clone(h.fields[i].values)
+	}
+	return nil
+}
+
+// Has reports whether a header is present
+func (h *Headers) Has(name string) bool {
+	return h.index(name) >= 0
+}
+
+// Set replaces the values of a header, keeping its position if present
+func (h *Headers) Set(name, value string) {
+	if i := h.index(name); i >= 0 {
+		h.fields[i].values = []string{value}
+		return
+	}
+	h.fields = append(h.fields, headerField{name: name, values: []string{value}})
+}
+
+// Add appends a value to a header
+func (h *Headers) Add(name, value string) {
+	if i := h.index(name); i >= 0 {
+		h.fields[i].values = append(h.fields[i].values, value)
+		return
+	}
+	h.fields = append(h.fields, headerField{name: name, values: []string{value}})
+}
+
+// Del removes a header
+func (h *Headers) Del(name string) {
+	if i := h.index(name); i >= 0 {
+		h.fields = slices.Delete(h.fields, i, i+1)
+	}
+}
+
+// Len returns the number of distinct header names
+func (h *Headers) Len() int {
+	if h == nil {
+		return 0
+	}
+	return len(h.fields)
+}
+
+// Names returns the header names in order
+func (h *Headers) Names() []string {
+	if h == nil {
+		return nil
+	}
+	names := make([]string, len(h.fields))
+	for i, field := range h.fields {
+		names[i] = field.name
+	}
+	return names
+}
+
+// Fields iterates over each header name with its values, in order
+func (h *Headers) Fields() iter.Seq2[string, []string] {
+	return func(yield func(string, []string) bool) {
+		if h == nil {
+			return
+		}
+		for _, field := range h.fields {
+			if !yield(field.name, slices.Clone(field.values)) {
+				return
+			}
+		}
+	}
+}
+
+// All iterates over every name/value pair, in order
+func (h *Headers) All() iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		if h == nil {
+			return
+		}
+		for _, field := range h.fields {
+			for _, value := range field.values {
+				if !yield(field.name, value) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Clone returns a deep copy
+func (h *Headers) Clone() *Headers {
+	clone := &Headers{}
+	if h == nil {
+		return clone
+	}
+	clone.fields = make([]headerField, len(h.fields))
+	for i, field := range h.fields {
+		clone.fields[i] = headerField{name: field.name, values: slices.Clone(field.values)}
+	}
+	return clone
+}
+
+// HTTPHeader converts to an http.Header with canonicalized names
+func (h *Headers) HTTPHeader() http.Header {
+	header := make(http.Header, h.Len())
+	for name, value := range h.All() {
+		header.Add(name, value)
+	}
+	return header
+}
+
+// Map flattens to a map, comma-joining repeated values. Set-Cookie keeps only
+// its last value, as its values cannot be joined.
+func (h *Headers) Map() map[string]string {
+	m := make(map[string]string, h.Len())
+	for name, values := range h.Fields() {
+		if strings.EqualFold(name, "Set-Cookie") {
+			m[name] = values[len(values)-1]
+			continue
+		}
+		m[name] = strings.Join(values, ", ")
+	}
+	return m
+}
+
+// Apply sets the headers on an outgoing request, replacing existing values and
+// routing Host to req.Host
+func (h *Headers) Apply(req *http.Request) {
+	for name, values := range h.Fields() {
+		if http.CanonicalHeaderKey(name) == "Host" {
+			req.Host = values[0]
+			continue
+		}
+		req.Header.Del(name)
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+}
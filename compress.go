@@ -0,0 +1,306 @@
+package headers
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression defaults
+const (
+	CompressMinSizeDefault = 1024
+	brotliLevelDefault     = 4
+)
+
+// compressEncodingsDefault is the server preference order for equally weighted codings
+var compressEncodingsDefault = []AcceptEncoding{
+	AcceptEncodingBrotli,
+	AcceptEncodingZstd,
+	AcceptEncodingGzip,
+	AcceptEncodingDeflate,
+}
+
+// Compressor is HTTP middleware that compresses responses using the coding
+// negotiated from the request's Accept-Encoding. Responses that are small,
+// already encoded, partial, marked no-transform, or of a content type that does
+// not benefit from compression (images, archives, ...) are sent unchanged.
+type Compressor struct {
+	// Encodings lists the offered codings in preference order; the defaults are
+	// br, zstd, gzip and deflate
+	Encodings []AcceptEncoding
+	// MinSize is the smallest body compressed, CompressMinSizeDefault when zero
+	MinSize int
+
+	pools sync.Map // AcceptEncoding -> *sync.Pool of encoders
+}
+
+// CompressHandler wraps next with a Compressor using default settings
+func CompressHandler(next http.Handler) http.Handler {
+	return (&Compressor{}).Handler(next)
+}
+
+// Handler returns next wrapped with response compression
+func (c *Compressor) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encodings := c.Encodings
+		if len(encodings) == 0 {
+			encodings = compressEncodingsDefault
+		}
+		minSize := c.MinSize
+		if minSize <= 0 {
+			minSize = CompressMinSizeDefault
+		}
+
+		cw := &compressWriter{
+			ResponseWriter: w,
+			compressor:     c,
+			encoding:       NegotiateEncoding(r.Header.Get("Accept-Encoding"), encodings...),
+			minSize:        minSize,
+			head:           r.Method == http.MethodHead,
+		}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// NegotiateEncoding picks the offered content coding with the highest weight in
+// an Accept-Encoding value, breaking ties by offer order. It returns
+// AcceptEncodingIdentity when nothing acceptable is offered.
+func NegotiateEncoding(acceptEncoding string, offered ...AcceptEncoding) AcceptEncoding {
+	weights := make(map[string]float64)
+	wildcard := -1.0
+	for _, item := range parseQualityList(acceptEncoding) {
+		value := item.value
+		if value == "x-gzip" {
+			value = string(AcceptEncodingGzip)
+		}
+		if value == "*" {
+			wildcard = item.q
+			continue
+		}
+		if _, seen := weights[value]; !seen {
+			weights[value] = item.q
+		}
+	}
+
+	best, bestQ := AcceptEncodingIdentity, 0.0
+	for _, encoding := range offered {
+		q, ok := weights[string(encoding)]
+		if !ok {
+			q = max(wildcard, 0)
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// encoder is the common interface of the supported compressors
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+// getEncoder returns a pooled encoder for the coding writing to w
+func (c *Compressor) getEncoder(encoding AcceptEncoding, w io.Writer) encoder {
+	pool, _ := c.pools.LoadOrStore(encoding, &sync.Pool{})
+	if enc, ok := pool.(*sync.Pool).Get().(encoder); ok {
+		enc.Reset(w)
+		return enc
+	}
+
+	switch encoding {
+	case AcceptEncodingBrotli:
+		return brotli.NewWriterLevel(w, brotliLevelDefault)
+	case AcceptEncodingZstd:
+		enc, _ := zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+		return enc
+	case AcceptEncodingDeflate:
+		return zlib.NewWriter(w)
+	default:
+		return gzip.NewWriter(w)
+	}
+}
+
+// putEncoder returns an encoder to its pool
+func (c *Compressor) putEncoder(encoding AcceptEncoding, enc encoder) {
+	enc.Reset(io.Discard)
+	if pool, ok := c.pools.Load(encoding); ok {
+		pool.(*sync.Pool).Put(enc)
+	}
+}
+
+// compressWriter buffers the start of a response until it can decide whether
+// to compress it
+type compressWriter struct {
+	http.ResponseWriter
+	compressor *Compressor
+	encoding   AcceptEncoding
+	minSize    int
+	head       bool
+
+	status  int
+	buf     []byte
+	decided bool
+	enc     encoder
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.decided || w.status != 0 {
+		return
+	}
+	// Informational responses pass straight through
+	if status < 200 {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.status = status
+
+	// Bodiless responses need no buffering
+	if status == http.StatusNoContent || status == http.StatusNotModified || w.head {
+		w.decide(false)
+	}
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if !w.decided {
+		w.buf = append(w.buf, p...)
+		if len(w.buf) < w.minSize {
+			return len(p), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if w.enc != nil {
+		return w.enc.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush commits to compression before MinSize is reached, since a streaming
+// response's final size cannot be known, and flushes the encoder
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		w.decide(true)
+	}
+	if w.enc != nil {
+		w.enc.Flush()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack hands the connection over, bypassing compression
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.decided = true
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// decide writes the response header, choosing whether to compress, then
+// writes any buffered body
+func (w *compressWriter) decide(large bool) error {
+	w.decided = true
+	h := w.Header()
+
+	if h.Get("Content-Type") == "" && len(w.buf) > 0 {
+		// Sniff from the uncompressed bytes, as net/http would
+		h.Set("Content-Type", string(DetectContentType(w.buf)))
+	}
+
+	compressible := w.compressible()
+	if compressible {
+		addVary(h, "Accept-Encoding")
+	}
+	if compressible && large && w.encoding != AcceptEncodingIdentity {
+		h.Set("Content-Encoding", string(w.encoding))
+		h.Del("Content-Length")
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			// The encoded representation is no longer byte-identical
+			h.Set("ETag", "W/"+etag)
+		}
+		w.enc = w.compressor.getEncoder(w.encoding, w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+	buf := w.buf
+	w.buf = nil
+	if w.enc != nil {
+		_, err := w.enc.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// compressible reports whether the response may be compressed at all
+func (w *compressWriter) compressible() bool {
+	h := w.Header()
+	switch {
+	case w.status < 200, w.status == http.StatusNoContent, w.status == http.StatusNotModified,
+		w.status == http.StatusPartialContent:
+		return false
+	case h.Get("Content-Encoding") != "", h.Get("Content-Range") != "":
+		return false
+	case ParseCacheControl(h.Values("Cache-Control")...).Has("no-transform"):
+		return false
+	}
+	return IsCompressible(ContentType(h.Get("Content-Type")))
+}
+
+// close finishes the response once the handler returns
+func (w *compressWriter) close() {
+	if !w.decided {
+		if w.status == 0 {
+			if len(w.buf) == 0 {
+				// Nothing was written; let net/http send its default response
+				return
+			}
+			w.status = http.StatusOK
+		}
+		w.decide(len(w.buf) >= w.minSize)
+	}
+	if w.enc != nil {
+		w.enc.Close()
+		w.compressor.putEncoder(w.encoding, w.enc)
+		w.enc = nil
+	}
+}
+
+// addVary adds a header name to Vary unless it is already listed
+func addVary(h http.Header, name string) {
+	for _, value := range h.Values("Vary") {
+		for existing := range strings.SplitSeq(value, ",") {
+			existing = strings.TrimSpace(existing)
+			if existing == "*" || strings.EqualFold(existing, name) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", name)
+}
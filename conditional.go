@@ -0,0 +1,270 @@
+package headers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Conditional request errors
+var (
+	ErrInvalidETag     = errors.New("headers: invalid entity tag")
+	ErrInvalidHTTPDate = errors.New("headers: invalid HTTP date")
+)
+
+// ETagAny is the "*" member of If-Match and If-None-Match
+const ETagAny = "*"
+
+// HTTP-date layouts from RFC 9110 section 5.6.7, preferred format first
+const (
+	HTTPDateIMFFixdate = http.TimeFormat
+	HTTPDateRFC850     = "Monday, 02-Jan-06 15:04:05 GMT"
+	HTTPDateASCTime    = "Mon Jan _2 15:04:05 2006"
+)
+
+// ETag represents an entity tag. Tag holds the opaque value without the
+// surrounding quotes; Weak marks a W/ prefixed tag.
+type ETag struct {
+	Tag  string
+	Weak bool
+}
+
+// NewETag returns a strong entity tag for the given opaque value
+func NewETag(tag string) ETag {
+	return ETag{Tag: tag}
+}
+
+// NewWeakETag returns a weak entity tag for the given opaque value
+func NewWeakETag(tag string) ETag {
+	return ETag{Tag: tag, Weak: true}
+}
+
+// String returns the entity tag in its quoted wire form, e.g. W/"abc"
+func (e ETag) String() string {
+	if e.Weak {
+		return `W/"` + e.Tag + `"`
+	}
+	return `"` + e.Tag + `"`
+}
+
+// Valid reports whether the opaque value only contains etagc characters
+func (e ETag) Valid() bool {
+	for i := 0; i < len(e.Tag); i++ {
+		if !isETagChar(e.Tag[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// StrongMatch reports whether both tags are strong and identical (RFC 9110 section 8.8.3.2)
+func (e ETag) StrongMatch(other ETag) bool {
+	return !e.Weak && !other.Weak && e.Tag == other.Tag
+}
+
+// WeakMatch reports whether the opaque values are identical regardless of weakness
+func (e ETag) WeakMatch(other ETag) bool {
+	return e.Tag == other.Tag
+}
+
+// ParseETag parses a single entity tag such as "abc" or W/"abc"
+func ParseETag(value string) (ETag, error) {
+	tag, rest, err := scanETag(strings.TrimSpace(value))
+	if err != nil {
+		return ETag{}, err
+	}
+	if rest != "" {
+		return ETag{}, fmt.Errorf("%w: %q", ErrInvalidETag, value)
+	}
+	return tag, nil
+}
+
+// scanETag reads one entity tag from the start of s and returns the remainder
+func scanETag(s string) (ETag, string, error) {
+	var tag ETag
+	if strings.HasPrefix(s, "W/") {
+		tag.Weak = true
+		s = s[2:]
+	}
+	if len(s) < 2 || s[0] != '"' {
+		return ETag{}, "", fmt.Errorf("%w: %q", ErrInvalidETag, s)
+	}
+
+	end := strings.IndexByte(s[1:], '"')
+	if end < 0 {
+		return ETag{}, "", fmt.Errorf("%w: %q", ErrInvalidETag, s)
+	}
+	tag.Tag = s[1 : end+1]
+	if !tag.Valid() {
+		return ETag{}, "", fmt.Errorf("%w: %q", ErrInvalidETag, s)
+	}
+	return tag, s[end+2:], nil
+}
+
+// isETagChar reports whether c is allowed inside an opaque-tag
+func isETagChar(c byte) bool {
+	return c == 0x21 || (c >= 0x23 && c != 0x7F)
+}
+
+// ETagList represents the value of an If-Match or If-None-Match header
+type ETagList struct {
+	Any  bool // The header was "*"
+	Tags []ETag
+}
+
+// ParseETagList parses an If-Match or If-None-Match header value
+func ParseETagList(value string) (ETagList, error) {
+	value = strings.TrimSpace(value)
+	if value == ETagAny {
+		return ETagList{Any: true}, nil
+	}
+
+	var list ETagList
+	s := value
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if s == "" {
+			break
+		}
+
+		tag, rest, err := scanETag(s)
+		if err != nil {
+			return ETagList{}, fmt.Errorf("%w: %q", ErrInvalidETag, value)
+		}
+		list.Tags = append(list.Tags, tag)
+
+		rest = strings.TrimLeft(rest, " \t")
+		if rest != "" && rest[0] != ',' {
+			return ETagList{}, fmt.Errorf("%w: %q", ErrInvalidETag, value)
+		}
+		s = rest
+	}
+
+	if len(list.Tags) == 0 {
+		return ETagList{}, fmt.Errorf("%w: %q", ErrInvalidETag, value)
+	}
+	return list, nil
+}
+
+// String returns the list in header form
+func (l ETagList) String() string {
+	if l.Any {
+		return ETagAny
+	}
+	tags := make([]string, 0, len(l.Tags))
+	for _, tag := range l.Tags {
+		tags = append(tags, tag.String())
+	}
+	return strings.Join(tags, ", ")
+}
+
+// MatchIfMatch evaluates the list as an If-Match condition, which uses strong comparison
+func (l ETagList) MatchIfMatch(current ETag) bool {
+	if l.Any {
+		return true
+	}
+	for _, tag := range l.Tags {
+		if tag.StrongMatch(current) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchIfNoneMatch reports whether current matches the list as an If-None-Match
+// condition, which uses weak comparison; a match means the condition is false
+func (l ETagList) MatchIfNoneMatch(current ETag) bool {
+	if l.Any {
+		return true
+	}
+	for _, tag := range l.Tags {
+		if tag.WeakMatch(current) {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatHTTPDate formats t as an IMF-fixdate, the only format senders may generate
+func FormatHTTPDate(t time.Time) string {
+	return t.UTC().Format(HTTPDateIMFFixdate)
+}
+
+// ParseHTTPDate parses an HTTP-date in IMF-fixdate, RFC 850 or asctime format
+func ParseHTTPDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	if t, err := time.Parse(HTTPDateIMFFixdate, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(HTTPDateRFC850, value); err == nil {
+		return adjustTwoDigitYear(t, time.Now()), nil
+	}
+	if t, err := time.Parse(HTTPDateASCTime, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%w: %q", ErrInvalidHTTPDate, value)
+}
+
+// adjustTwoDigitYear applies the RFC 9110 rule that a two-digit year appearing
+// more than 50 years in the future is the most recent past year with the same
+// last two digits
+func adjustTwoDigitYear(t, now time.Time) time.Time {
+	year := now.Year() - now.Year()%100 + t.Year()%100
+	if year > now.Year()+50 {
+		year -= 100
+	}
+	return time.Date(year, t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+}
+
+// SetIfModifiedSince sets If-Modified-Since from a time
+func (o *HeaderOpts) SetIfModifiedSince(t time.Time) *HeaderOpts {
+	o.IfModifiedSince = FormatHTTPDate(t)
+	return o
+}
+
+// SetIfUnmodifiedSince sets If-Unmodified-Since from a time
+func (o *HeaderOpts) SetIfUnmodifiedSince(t time.Time) *HeaderOpts {
+	o.IfUnmodifiedSince = FormatHTTPDate(t)
+	return o
+}
+
+// SetIfNoneMatch sets If-None-Match to the given entity tags
+func (o *HeaderOpts) SetIfNoneMatch(tags ...ETag) *HeaderOpts {
+	o.IfNoneMatch = ETagList{Tags: tags}.String()
+	return o
+}
+
+// SetIfNoneMatchAny sets If-None-Match to "*"
+func (o *HeaderOpts) SetIfNoneMatchAny() *HeaderOpts {
+	o.IfNoneMatch = ETagAny
+	return o
+}
+
+// SetIfMatch sets If-Match to the given entity tags
+func (o *HeaderOpts) SetIfMatch(tags ...ETag) *HeaderOpts {
+	o.IfMatch = ETagList{Tags: tags}.String()
+	return o
+}
+
+// SetIfMatchAny sets If-Match to "*"
+func (o *HeaderOpts) SetIfMatchAny() *HeaderOpts {
+	o.IfMatch = ETagAny
+	return o
+}
+
+// SetIfRangeETag sets If-Range to a strong entity tag; weak tags are ignored
+func (o *HeaderOpts) SetIfRangeETag(tag ETag) *HeaderOpts {
+	if !tag.Weak {
+		o.IfRange = tag.String()
+	}
+	return o
+}
+
+// SetIfRangeDate sets If-Range to a Last-Modified time
+func (o *HeaderOpts) SetIfRangeDate(t time.Time) *HeaderOpts {
+	o.IfRange = FormatHTTPDate(t)
+	return o
+}
@@ -23,6 +23,50 @@ const (
 	UDID = OSName + "/" + BrowserName
 )
 
+// Profile Constants
+const (
+	ProfileChrome  Profile = "chrome"
+	ProfileFirefox Profile = "firefox"
+	ProfileSafari  Profile = "safari"
+	ProfileEdge    Profile = "edge"
+)
+
+// RequestKind Constants
+const (
+	RequestNavigation    RequestKind = "navigation"
+	RequestIFrame        RequestKind = "iframe"
+	RequestFetch         RequestKind = "fetch"
+	RequestXHR           RequestKind = "xhr"
+	RequestImage         RequestKind = "image"
+	RequestScript        RequestKind = "script"
+	RequestModuleScript  RequestKind = "module-script"
+	RequestStyle         RequestKind = "style"
+	RequestFont          RequestKind = "font"
+	RequestAudio         RequestKind = "audio"
+	RequestVideo         RequestKind = "video"
+	RequestManifest      RequestKind = "manifest"
+	RequestWorker        RequestKind = "worker"
+	RequestSharedWorker  RequestKind = "sharedworker"
+	RequestServiceWorker RequestKind = "serviceworker"
+	RequestObject        RequestKind = "object"
+	RequestWebSocket     RequestKind = "websocket"
+)
+
+// ReferrerPolicy Constants
+const (
+	ReferrerPolicyNoReferrer                  ReferrerPolicy = "no-referrer"
+	ReferrerPolicyNoReferrerWhenDowngrade     ReferrerPolicy = "no-referrer-when-downgrade"
+	ReferrerPolicySameOrigin                  ReferrerPolicy = "same-origin"
+	ReferrerPolicyOrigin                      ReferrerPolicy = "origin"
+	ReferrerPolicyStrictOrigin                ReferrerPolicy = "strict-origin"
+	ReferrerPolicyOriginWhenCrossOrigin       ReferrerPolicy = "origin-when-cross-origin"
+	ReferrerPolicyStrictOriginWhenCrossOrigin ReferrerPolicy = "strict-origin-when-cross-origin"
+	ReferrerPolicyUnsafeURL                   ReferrerPolicy = "unsafe-url"
+
+	// ReferrerPolicyDefault is the policy browsers apply when none is set
+	ReferrerPolicyDefault = ReferrerPolicyStrictOriginWhenCrossOrigin
+)
+
 // Common Header Values
 const (
 	AcceptDefault         = "*/*"
@@ -143,6 +187,9 @@ const (
 	CacheControlPrivate              CacheControl = "private"
 	CacheControlImmutable            CacheControl = "immutable"
 	CacheControlStaleWhileRevalidate CacheControl = "stale-while-revalidate=86400"
+	CacheControlNoTransform          CacheControl = "no-transform"
+	CacheControlOnlyIfCached         CacheControl = "only-if-cached"
+	CacheControlMaxStaleAny          CacheControl = "max-stale"
 )
 
 // Authorization Constants
@@ -177,6 +224,7 @@ const (
 	SecFetchDestServiceWorker SecFetchDest = "serviceworker"
 	SecFetchDestSharedWorker  SecFetchDest = "sharedworker"
 	SecFetchDestWorker        SecFetchDest = "worker"
+	SecFetchDestWebSocket     SecFetchDest = "websocket"
 )
 
 // SecFetchMode Constants
@@ -190,6 +238,8 @@ const (
 
 // SecFetchSite Constants
 const (
+	SecFetchSiteCrossSite SecFetchSite = "cross-site"
+	// Deprecated: browsers send "cross-site"; use SecFetchSiteCrossSite.
 	SecFetchSiteCrossOrigin SecFetchSite = "cross-origin"
 	SecFetchSiteSameOrigin  SecFetchSite = "same-origin"
 	SecFetchSiteSameSite    SecFetchSite = "same-site"
@@ -214,6 +264,16 @@ const (
 	XRequestedWithFlash          XRequestedWith = "ShockwaveFlash"
 )
 
+// Priority Constants (as sent by Chrome for each network priority)
+const (
+	PriorityHighest            Priority = "u=0"
+	PriorityHighestIncremental Priority = "u=0, i"
+	PriorityMedium             Priority = "u=1"
+	PriorityMediumIncremental  Priority = "u=1, i"
+	PriorityLowIncremental     Priority = "u=2, i"
+	PriorityLowestIncremental  Priority = "i"
+)
+
 // XFrameOptions Constants
 const (
 	XFrameOptionsDeny       XFrameOptions = "DENY"
@@ -310,6 +370,29 @@ const (
 	SecCHPrefersReducedMotionReduce       SecCHPrefersReducedMotion = "reduce"
 )
 
+// Per-destination Accept Constants
+const (
+	AcceptChromeDocument Accept = "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7"
+	AcceptChromeImage    Accept = "image/avif,image/webp,image/apng,image/svg+xml,image/*,*/*;q=0.8"
+
+	AcceptFirefoxDocument Accept = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+	AcceptFirefoxImage    Accept = "image/avif,image/webp,image/png,image/svg+xml,image/*;q=0.8,*/*;q=0.5"
+	AcceptFirefoxFont     Accept = "application/font-woff2;q=1.0,application/font-woff;q=0.9,*/*;q=0.8"
+	AcceptFirefoxAudio    Accept = "audio/webm,audio/ogg,audio/wav,audio/*;q=0.9,application/ogg;q=0.7,video/*;q=0.6,*/*;q=0.5"
+	AcceptFirefoxVideo    Accept = "video/webm,video/ogg,video/*;q=0.9,application/ogg;q=0.7,audio/*;q=0.6,*/*;q=0.5"
+
+	AcceptSafariDocument Accept = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+	AcceptSafariImage    Accept = "image/webp,image/avif,image/jxl,image/heic,image/heic-sequence,video/*;q=0.8,image/png,image/svg+xml,image/*;q=0.8,*/*;q=0.5"
+
+	AcceptStyle Accept = "text/css,*/*;q=0.1"
+)
+
+// Media Accept-Encoding Constants (media is fetched in byte ranges, so never compressed)
+const (
+	AcceptEncodingChromeMedia AcceptEncoding = "identity;q=1, *;q=0"
+	AcceptEncodingMedia       AcceptEncoding = "identity"
+)
+
 // Custom API Header Names (commonly used)
 const (
 	HeaderAPIKey             = "X-API-Key"
@@ -322,3 +405,17 @@ const (
 	HeaderRateLimitRemaining = "X-RateLimit-Remaining"
 	HeaderRateLimitReset     = "X-RateLimit-Reset"
 )
+
+// Trace Context Header Names (W3C)
+const (
+	HeaderTraceParent = "traceparent"
+	HeaderTraceState  = "tracestate"
+)
+
+// Forwarding Header Names
+const (
+	HeaderForwarded       = "Forwarded"
+	HeaderXForwardedFor   = "X-Forwarded-For"
+	HeaderXForwardedProto = "X-Forwarded-Proto"
+	HeaderXForwardedHost  = "X-Forwarded-Host"
+)
@@ -0,0 +1,233 @@
+package headers
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// DecompressLimitDefault caps the decoded size of a single response body
+const DecompressLimitDefault int64 = 256 << 20
+
+// Decompression errors
+var (
+	ErrDecompressedTooLarge = errors.New("headers: decompressed body exceeds limit")
+	ErrUnsupportedEncoding  = errors.New("headers: unsupported content encoding")
+)
+
+// DecompressTransport is an http.RoundTripper that advertises Accept-Encoding
+// and transparently decodes gzip, deflate, br and zstd response bodies,
+// including stacked codings such as "gzip, br".
+//
+// Go's http.Transport only decodes gzip, and only when it added Accept-Encoding
+// itself, so requests built with AcceptEncodingDefault otherwise come back
+// compressed. Decoded responses have Content-Encoding and Content-Length removed,
+// ContentLength set to -1 and Uncompressed set to true.
+type DecompressTransport struct {
+	Transport      http.RoundTripper // Underlying transport, http.DefaultTransport when nil
+	AcceptEncoding AcceptEncoding    // Sent when the request has none, AcceptEncodingAll when empty
+
+	// MaxBytes caps the decoded body size, DecompressLimitDefault when zero and
+	// unlimited when negative. MaxRatio, when positive, caps the decoded size
+	// relative to the encoded bytes read, to stop decompression bombs early.
+	MaxBytes int64
+	MaxRatio int64
+}
+
+// NewDecompressTransport creates a DecompressTransport over next
+func NewDecompressTransport(next http.RoundTripper) *DecompressTransport {
+	return &DecompressTransport{Transport: next}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *DecompressTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	if req.Header.Get("Accept-Encoding") == "" {
+		acceptEncoding := t.AcceptEncoding
+		if acceptEncoding == "" {
+			acceptEncoding = AcceptEncodingAll
+		}
+		req = req.Clone(req.Context())
+		req.Header.Set("Accept-Encoding", string(acceptEncoding))
+	}
+
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Partial content cannot be decoded on its own
+	if req.Method == http.MethodHead || resp.StatusCode == http.StatusPartialContent || resp.Body == nil || resp.Body == http.NoBody {
+		return resp, nil
+	}
+
+	codings := parseContentEncoding(resp.Header.Values("Content-Encoding"))
+	if len(codings) == 0 {
+		return resp, nil
+	}
+	for _, coding := range codings {
+		if !isSupportedEncoding(coding) {
+			// Leave bodies we cannot fully decode untouched
+			return resp, nil
+		}
+	}
+
+	limit := t.MaxBytes
+	if limit == 0 {
+		limit = DecompressLimitDefault
+	}
+	resp.Body = &decodingBody{
+		body:     resp.Body,
+		encoded:  &countingReader{r: resp.Body},
+		codings:  codings,
+		limit:    limit,
+		maxRatio: t.MaxRatio,
+	}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+// parseContentEncoding returns the lowercase codings in the order they were applied,
+// dropping identity
+func parseContentEncoding(values []string) []string {
+	var codings []string
+	for _, value := range values {
+		for coding := range strings.SplitSeq(value, ",") {
+			coding = strings.ToLower(strings.TrimSpace(coding))
+			if coding != "" && coding != "identity" {
+				codings = append(codings, coding)
+			}
+		}
+	}
+	return codings
+}
+
+// isSupportedEncoding reports whether a content coding can be decoded
+func isSupportedEncoding(coding string) bool {
+	switch coding {
+	case "gzip", "x-gzip", "deflate", "br", "zstd":
+		return true
+	}
+	return false
+}
+
+// NewDecoder wraps r with a decoder for a single content coding
+func NewDecoder(coding string, r io.Reader) (io.ReadCloser, error) {
+	switch strings.ToLower(coding) {
+	case "gzip", "x-gzip":
+		return gzip.NewReader(r)
+	case "deflate":
+		return newDeflateReader(r)
+	case "br":
+		return io.NopCloser(brotli.NewReader(r)), nil
+	case "zstd":
+		dec, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return dec.IOReadCloser(), nil
+	case "identity", "":
+		return io.NopCloser(r), nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnsupportedEncoding, coding)
+}
+
+// newDeflateReader decodes HTTP deflate, which should be zlib-wrapped but is
+// sent as raw DEFLATE by some servers
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err != nil && len(header) < 2 {
+		return nil, err
+	}
+	if header[0]&0x0F == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// decodingBody lazily builds the decoder chain on first read and enforces limits
+type decodingBody struct {
+	body     io.ReadCloser
+	encoded  *countingReader
+	codings  []string
+	limit    int64
+	maxRatio int64
+
+	reader   io.Reader
+	decoders []io.Closer
+	decoded  int64
+	err      error
+}
+
+func (b *decodingBody) Read(p []byte) (int, error) {
+	if b.reader == nil && b.err == nil {
+		b.err = b.init()
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+
+	n, err := b.reader.Read(p)
+	b.decoded += int64(n)
+	if b.limit > 0 && b.decoded > b.limit {
+		b.err = ErrDecompressedTooLarge
+		return n - int(b.decoded-b.limit), b.err
+	}
+	// Allow a small floor so tiny encoded prefixes do not trip the ratio
+	if b.maxRatio > 0 && b.decoded > b.maxRatio*max(b.encoded.n, 1<<10) {
+		b.err = ErrDecompressedTooLarge
+		return 0, b.err
+	}
+	return n, err
+}
+
+// init stacks decoders in reverse order of application
+func (b *decodingBody) init() error {
+	var r io.Reader = b.encoded
+	for i := len(b.codings) - 1; i >= 0; i-- {
+		dec, err := NewDecoder(b.codings[i], r)
+		if err != nil {
+			return err
+		}
+		b.decoders = append(b.decoders, dec)
+		r = dec
+	}
+	b.reader = r
+	return nil
+}
+
+func (b *decodingBody) Close() error {
+	for i := len(b.decoders) - 1; i >= 0; i-- {
+		b.decoders[i].Close()
+	}
+	return b.body.Close()
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,90 @@
+package headers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// DiskCacheStore is a CacheStore persisting each entry as a file in a directory.
+// The CacheStore interface has no error results, so I/O failures surface as
+// cache misses and unsaved entries.
+type DiskCacheStore struct {
+	mu  sync.RWMutex
+	dir string
+}
+
+// diskCacheRecord is the on-disk form of an entry; the key guards against hash collisions
+type diskCacheRecord struct {
+	Key   string
+	Entry CacheEntry
+}
+
+// NewDiskCacheStore creates a store in dir, creating the directory if needed
+func NewDiskCacheStore(dir string) (*DiskCacheStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DiskCacheStore{dir: dir}, nil
+}
+
+// Get reads the entry stored under key
+func (s *DiskCacheStore) Get(key string) (*CacheEntry, bool) {
+	s.mu.RLock()
+	data, err := os.ReadFile(s.path(key))
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, false
+	}
+
+	var record diskCacheRecord
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&record); err != nil || record.Key != key {
+		return nil, false
+	}
+	return &record.Entry, true
+}
+
+// Set writes entry under key, replacing any previous file atomically
+func (s *DiskCacheStore) Set(key string, entry *CacheEntry) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(diskCacheRecord{Key: key, Entry: *entry}); err != nil {
+		return
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(buf.Bytes())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Rename(tmp.Name(), s.path(key)); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// Delete removes the entry stored under key
+func (s *DiskCacheStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	os.Remove(s.path(key))
+}
+
+// path returns the file holding the entry for key
+func (s *DiskCacheStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:]))
+}
@@ -0,0 +1,375 @@
+package headers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrInvalidContentDisposition is returned for Content-Disposition values without a type
+var ErrInvalidContentDisposition = errors.New("headers: invalid content disposition")
+
+// ContentDispositionSpec represents a parsed Content-Disposition header. Type is
+// lowercase; Params holds lowercase parameter names with decoded values, with
+// extended (name*) parameters already decoded into their own entries.
+type ContentDispositionSpec struct {
+	Type   ContentDisposition
+	Params map[string]string
+}
+
+// NewContentDisposition builds a Content-Disposition value for the given type and
+// filename. The filename is reduced to its last path component; when it is not
+// plain ASCII an RFC 5987 filename* parameter is added next to an ASCII fallback.
+func NewContentDisposition(disposition ContentDisposition, filename string) ContentDisposition {
+	filename = SanitizeFilename(filename)
+	if filename == "" {
+		return disposition
+	}
+
+	value := string(disposition) + "; filename=" + quote(asciiFallback(filename))
+	if needsExtendedFilename(filename) {
+		value += "; filename*=UTF-8''" + encodeRFC5987(filename)
+	}
+	return ContentDisposition(value)
+}
+
+// AttachmentFilename builds an attachment Content-Disposition with a filename
+func AttachmentFilename(filename string) ContentDisposition {
+	return NewContentDisposition(ContentDispositionAttachment, filename)
+}
+
+// InlineFilename builds an inline Content-Disposition with a filename
+func InlineFilename(filename string) ContentDisposition {
+	return NewContentDisposition(ContentDispositionInline, filename)
+}
+
+// FormDataDisposition builds the Content-Disposition of a multipart/form-data
+// part the way browsers do: UTF-8 names with '"', CR and LF percent-encoded
+// (HTML multipart/form-data encoding algorithm) and no filename* parameter
+func FormDataDisposition(name, filename string) ContentDisposition {
+	value := string(ContentDispositionFormData) + `; name="` + escapeFormDataName(name) + `"`
+	if filename != "" {
+		value += `; filename="` + escapeFormDataName(filename) + `"`
+	}
+	return ContentDisposition(value)
+}
+
+// ParseContentDisposition parses a Content-Disposition value, tolerating the
+// malformed quoting common in the wild: unquoted values with spaces, unterminated
+// quotes, stray semicolons, raw UTF-8 and RFC 2231 continuations
+func ParseContentDisposition(value string) (ContentDispositionSpec, error) {
+	typ, rest, _ := strings.Cut(value, ";")
+	typ = strings.ToLower(strings.TrimSpace(typ))
+	if typ == "" || strings.Contains(typ, "=") {
+		return ContentDispositionSpec{}, fmt.Errorf("%w: %q", ErrInvalidContentDisposition, value)
+	}
+
+	spec := ContentDispositionSpec{
+		Type:   ContentDisposition(typ),
+		Params: make(map[string]string),
+	}
+	continuations := make(map[string]map[int]string)
+	extended := make(map[string]bool)
+
+	for _, param := range splitDispositionParams(rest) {
+		name, raw, ok := strings.Cut(param, "=")
+		if !ok {
+			continue
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		raw = strings.TrimSpace(raw)
+
+		// RFC 2231 continuations: name*0, name*1*, ...
+		if base, index, encoded, ok := splitContinuation(name); ok {
+			if continuations[base] == nil {
+				continuations[base] = make(map[int]string)
+			}
+			if encoded {
+				extended[base] = true
+			}
+			continuations[base][index] = lenientUnquote(raw)
+			continue
+		}
+
+		if base, ok := strings.CutSuffix(name, "*"); ok {
+			if decoded, ok := decodeRFC5987(lenientUnquote(raw)); ok {
+				spec.Params[base+"*"] = decoded
+			}
+			continue
+		}
+		if _, seen := spec.Params[name]; !seen {
+			spec.Params[name] = lenientUnquote(raw)
+		}
+	}
+
+	for base, parts := range continuations {
+		indexes := make([]int, 0, len(parts))
+		for index := range parts {
+			indexes = append(indexes, index)
+		}
+		slices.Sort(indexes)
+
+		var sb strings.Builder
+		for i, index := range indexes {
+			if index != i {
+				// Continuations must be contiguous from zero
+				break
+			}
+			sb.WriteString(parts[index])
+		}
+		joined := sb.String()
+		if extended[base] {
+			decoded, ok := decodeRFC5987(joined)
+			if !ok {
+				continue
+			}
+			joined = decoded
+		}
+		// Only continuations with an encoded part carry the extended form
+		key := base
+		if extended[base] {
+			key = base + "*"
+		}
+		if _, seen := spec.Params[key]; !seen {
+			spec.Params[key] = joined
+		}
+	}
+	return spec, nil
+}
+
+// splitDispositionParams splits parameters at semicolons outside quoted
+// strings. A quote that is never closed ends at the next semicolon starting a
+// name=value parameter instead of swallowing the rest of the value.
+func splitDispositionParams(s string) []string {
+	var (
+		parts        []string
+		start        int
+		quoted       bool
+		unterminated bool
+		escaped      bool
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && c == '\\':
+			escaped = true
+		case c == '"' && !unterminated:
+			quoted = !quoted
+			if quoted && !hasClosingQuote(s[i+1:]) {
+				quoted, unterminated = false, true
+			}
+		case c == ';' && !quoted && (!unterminated || startsParam(s[i+1:])):
+			if part := strings.TrimSpace(s[start:i]); part != "" {
+				parts = append(parts, part)
+			}
+			start = i + 1
+			unterminated = false
+		}
+	}
+	if part := strings.TrimSpace(s[start:]); part != "" {
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// hasClosingQuote reports whether s, the rest of a quoted string, contains its
+// unescaped closing quote
+func hasClosingQuote(s string) bool {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return true
+		}
+	}
+	return false
+}
+
+// startsParam reports whether s begins with a parameter name followed by "="
+func startsParam(s string) bool {
+	s = strings.TrimLeft(s, " \t")
+	n := 0
+	for n < len(s) && isTokenChar(s[n]) {
+		n++
+	}
+	return n > 0 && strings.HasPrefix(strings.TrimLeft(s[n:], " \t"), "=")
+}
+
+// Filename returns the sanitized filename, preferring filename* over filename
+func (c ContentDispositionSpec) Filename() string {
+	if name, ok := c.Params["filename*"]; ok && name != "" {
+		return SanitizeFilename(name)
+	}
+	return SanitizeFilename(c.Params["filename"])
+}
+
+// Name returns the form field name of a form-data disposition
+func (c ContentDispositionSpec) Name() string {
+	return c.Params["name"]
+}
+
+// IsAttachment reports whether the disposition asks for the content to be saved
+func (c ContentDispositionSpec) IsAttachment() bool {
+	return c.Type == ContentDispositionAttachment
+}
+
+// SanitizeFilename reduces a filename to a safe last path component: directory
+// parts (with either slash), control characters and leading dots are removed,
+// and names that end up empty are returned as ""
+func SanitizeFilename(filename string) string {
+	filename = strings.ReplaceAll(filename, `\`, "/")
+	filename = path.Base(filename)
+
+	filename = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, filename)
+	filename = strings.TrimLeft(strings.TrimSpace(filename), ".")
+
+	if filename == "/" {
+		return ""
+	}
+	return filename
+}
+
+// splitContinuation recognizes RFC 2231 parameter continuations
+func splitContinuation(name string) (base string, index int, encoded bool, ok bool) {
+	base, suffix, found := strings.Cut(name, "*")
+	if !found || suffix == "" {
+		return "", 0, false, false
+	}
+	suffix, encoded = strings.CutSuffix(suffix, "*")
+	index, err := strconv.Atoi(suffix)
+	if err != nil || index < 0 {
+		return "", 0, false, false
+	}
+	return base, index, encoded, true
+}
+
+// lenientUnquote unquotes a value, accepting unterminated quotes and unquoted
+// values. Only \" and \\ are unescaped so that unescaped Windows paths survive.
+func lenientUnquote(s string) string {
+	if !strings.HasPrefix(s, `"`) {
+		return s
+	}
+	s = s[1:]
+	if strings.HasSuffix(s, `"`) && !strings.HasSuffix(s, `\"`) {
+		s = s[:len(s)-1]
+	}
+	return strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(s)
+}
+
+// decodeRFC5987 decodes an ext-value of the form charset'language'percent-encoded
+func decodeRFC5987(s string) (string, bool) {
+	charset, rest, ok := strings.Cut(s, "'")
+	if !ok {
+		return "", false
+	}
+	_, encoded, ok := strings.Cut(rest, "'")
+	if !ok {
+		return "", false
+	}
+
+	raw, err := url.PathUnescape(encoded)
+	if err != nil {
+		return "", false
+	}
+	switch strings.ToLower(charset) {
+	case "utf-8", "":
+		if !utf8.ValidString(raw) {
+			return "", false
+		}
+		return raw, true
+	case "iso-8859-1", "us-ascii":
+		// Latin-1 bytes map directly onto the first 256 code points
+		runes := make([]rune, 0, len(raw))
+		for i := 0; i < len(raw); i++ {
+			runes = append(runes, rune(raw[i]))
+		}
+		return string(runes), true
+	}
+	return "", false
+}
+
+// encodeRFC5987 percent-encodes everything except attr-char
+func encodeRFC5987(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if isAttrChar(c) {
+			sb.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&sb, "%%%02X", c)
+	}
+	return sb.String()
+}
+
+// isAttrChar reports whether c may appear unencoded in an RFC 5987 value
+func isAttrChar(c byte) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", c) >= 0
+}
+
+// needsExtendedFilename reports whether a filename needs the filename* form
+func needsExtendedFilename(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf || s[i] == '%' {
+			return true
+		}
+	}
+	return false
+}
+
+// asciiFallback replaces non-ASCII characters for the plain filename parameter,
+// mapping common accented letters to their base letter
+func asciiFallback(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r < utf8.RuneSelf && r != '%':
+			return r
+		case r == '%':
+			return '_'
+		}
+		if base, ok := latinBase[r]; ok {
+			return base
+		}
+		return '_'
+	}, s)
+}
+
+// latinBase maps accented Latin-1 letters to their unaccented form
+var latinBase = func() map[rune]rune {
+	m := make(map[rune]rune)
+	groups := map[rune]string{
+		'A': "ÀÁÂÃÄÅ", 'a': "àáâãäå", 'C': "Ç", 'c': "ç",
+		'E': "ÈÉÊË", 'e': "èéêë", 'I': "ÌÍÎÏ", 'i': "ìíîï",
+		'N': "Ñ", 'n': "ñ", 'O': "ÒÓÔÕÖØ", 'o': "òóôõöø",
+		'U': "ÙÚÛÜ", 'u': "ùúûü", 'Y': "Ý", 'y': "ýÿ",
+	}
+	for base, letters := range groups {
+		for _, r := range letters {
+			m[r] = base
+		}
+	}
+	return m
+}()
+
+// escapeFormDataName applies the HTML form-data escaping of names and filenames
+func escapeFormDataName(s string) string {
+	return strings.NewReplacer(`"`, "%22", "\r", "%0D", "\n", "%0A").Replace(s)
+}
@@ -0,0 +1,434 @@
+package headers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Download defaults
+const (
+	DownloadChunkSizeDefault   int64 = 8 << 20
+	DownloadConcurrencyDefault       = 4
+
+	downloadPartSuffix  = ".part"
+	downloadStateSuffix = ".part.json"
+)
+
+// Download errors
+var (
+	ErrResourceChanged = errors.New("headers: resource changed during download")
+	ErrSizeMismatch    = errors.New("headers: downloaded size does not match")
+)
+
+// Downloader fetches a resource into a file, splitting it into byte ranges that
+// are fetched concurrently when the server supports them, and resuming partial
+// downloads left behind by an earlier attempt.
+//
+// Partial data is kept in "<path>.part" and the resume state (validators and
+// completed chunks) in "<path>.part.json" until the download is verified.
+type Downloader struct {
+	Client      *http.Client
+	Builder     *Builder
+	Opts        HeaderOpts // Options applied to every request
+	ChunkSize   int64
+	Concurrency int
+}
+
+// DownloadResult describes a completed download
+type DownloadResult struct {
+	Size         int64
+	ETag         string
+	LastModified string
+	Ranged       bool // Fetched in concurrent byte ranges
+	Resumed      bool // Continued from a previous partial download
+}
+
+// downloadState is the resume state persisted next to the partial file
+type downloadState struct {
+	URL          string `json:"url"`
+	Size         int64  `json:"size"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	ChunkSize    int64  `json:"chunk_size"`
+	Completed    []int  `json:"completed"`
+}
+
+// resourceInfo is what the server reported about the resource
+type resourceInfo struct {
+	size         int64
+	acceptRanges bool
+	etag         string
+	lastModified string
+}
+
+// NewDownloader creates a Downloader using the given client and header builder;
+// nil values fall back to http.DefaultClient and an empty Builder
+func NewDownloader(client *http.Client, builder *Builder) *Downloader {
+	return &Downloader{
+		Client:      client,
+		Builder:     builder,
+		ChunkSize:   DownloadChunkSizeDefault,
+		Concurrency: DownloadConcurrencyDefault,
+	}
+}
+
+// Download fetches url into path, resuming a previous partial download when the
+// saved validators still match the resource
+func (d *Downloader) Download(ctx context.Context, url, path string) (*DownloadResult, error) {
+	info, err := d.head(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without a known size and byte-range support, fall back to a single stream
+	if !info.acceptRanges || info.size <= 0 {
+		return d.downloadStream(ctx, url, path)
+	}
+
+	result, err := d.downloadRanges(ctx, url, path, info)
+	if errors.Is(err, ErrResourceChanged) {
+		// The saved partial data is stale; start over once with fresh validators
+		d.discard(path)
+		if info, err = d.head(ctx, url); err != nil {
+			return nil, err
+		}
+		if !info.acceptRanges || info.size <= 0 {
+			return d.downloadStream(ctx, url, path)
+		}
+		return d.downloadRanges(ctx, url, path, info)
+	}
+	return result, err
+}
+
+// head asks the server for the resource size, validators and range support
+func (d *Downloader) head(ctx context.Context, url string) (resourceInfo, error) {
+	resp, err := d.do(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return resourceInfo{}, err
+	}
+	resp.Body.Close()
+
+	// Servers that refuse HEAD are treated as not supporting ranges
+	if resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented {
+		return resourceInfo{size: -1}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resourceInfo{}, fmt.Errorf("headers: HEAD %s: unexpected status %s", url, resp.Status)
+	}
+
+	return resourceInfo{
+		size:         resp.ContentLength,
+		acceptRanges: acceptsByteRanges(resp.Header),
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}, nil
+}
+
+// acceptsByteRanges reports whether Accept-Ranges lists the bytes unit
+func acceptsByteRanges(h http.Header) bool {
+	for _, value := range h.Values("Accept-Ranges") {
+		for unit := range strings.SplitSeq(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(unit), "bytes") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// downloadRanges fetches the missing chunks of the resource concurrently
+func (d *Downloader) downloadRanges(ctx context.Context, url, path string, info resourceInfo) (*DownloadResult, error) {
+	chunkSize := d.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DownloadChunkSizeDefault
+	}
+	concurrency := d.Concurrency
+	if concurrency <= 0 {
+		concurrency = DownloadConcurrencyDefault
+	}
+
+	state, resumed := d.loadState(path, url, info, chunkSize)
+	ifRange := ifRangeValidator(state.ETag, state.LastModified)
+
+	file, err := os.OpenFile(path+downloadPartSuffix, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if !resumed {
+		if err := file.Truncate(0); err != nil {
+			return nil, err
+		}
+	}
+	if err := file.Truncate(info.size); err != nil {
+		return nil, err
+	}
+
+	done := make(map[int]bool, len(state.Completed))
+	for _, index := range state.Completed {
+		done[index] = true
+	}
+	chunks := int((info.size + chunkSize - 1) / chunkSize)
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		jobs = make(chan int)
+	)
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				first := int64(index) * chunkSize
+				last := min(first+chunkSize, info.size) - 1
+				if err := d.fetchChunk(ctx, url, file, first, last, info.size, ifRange); err != nil {
+					cancel(err)
+					continue
+				}
+
+				mu.Lock()
+				state.Completed = append(state.Completed, index)
+				err := d.saveState(path, state)
+				mu.Unlock()
+				if err != nil {
+					cancel(err)
+				}
+			}
+		}()
+	}
+
+	for index := range chunks {
+		if done[index] {
+			continue
+		}
+		select {
+		case jobs <- index:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := context.Cause(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := d.finish(file, path, info.size); err != nil {
+		return nil, err
+	}
+	return &DownloadResult{
+		Size:         info.size,
+		ETag:         state.ETag,
+		LastModified: state.LastModified,
+		Ranged:       true,
+		Resumed:      resumed,
+	}, nil
+}
+
+// fetchChunk downloads bytes first-last and writes them at their offset
+func (d *Downloader) fetchChunk(ctx context.Context, url string, file *os.File, first, last, size int64, ifRange string) error {
+	opts := d.Opts
+	opts.Range = NewRange(BytesBetween(first, last))
+	opts.IfRange = ifRange
+
+	resp, err := d.do(ctx, http.MethodGet, url, &opts)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		// If-Range failed (or ranges were dropped), so the full representation came back
+		return ErrResourceChanged
+	default:
+		return fmt.Errorf("headers: GET %s bytes %d-%d: unexpected status %s", url, first, last, resp.Status)
+	}
+
+	cr, err := ParseContentRange(resp.Header.Get("Content-Range"))
+	if err != nil {
+		return err
+	}
+	if cr.Unsatisfied || cr.First != first || cr.Last != last {
+		return fmt.Errorf("%w: got %q for bytes %d-%d", ErrInvalidContentRange, cr.ContentRange(), first, last)
+	}
+	if cr.Size >= 0 && cr.Size != size {
+		return ErrResourceChanged
+	}
+
+	n, err := io.Copy(io.NewOffsetWriter(file, first), io.LimitReader(resp.Body, cr.Length()))
+	if err != nil {
+		return err
+	}
+	if n != cr.Length() {
+		return fmt.Errorf("%w: got %d of %d bytes at offset %d", ErrSizeMismatch, n, cr.Length(), first)
+	}
+	return nil
+}
+
+// downloadStream fetches the whole resource in a single request
+func (d *Downloader) downloadStream(ctx context.Context, url, path string) (*DownloadResult, error) {
+	d.discard(path)
+
+	resp, err := d.do(ctx, http.MethodGet, url, &d.Opts)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("headers: GET %s: unexpected status %s", url, resp.Status)
+	}
+
+	file, err := os.Create(path + downloadPartSuffix)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	n, err := io.Copy(file, resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	size := n
+	if resp.ContentLength >= 0 {
+		size = resp.ContentLength
+	}
+	if err := d.finish(file, path, size); err != nil {
+		return nil, err
+	}
+	return &DownloadResult{
+		Size:         size,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}, nil
+}
+
+// finish verifies the partial file size and moves it into place
+func (d *Downloader) finish(file *os.File, path string, size int64) error {
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if stat.Size() != size {
+		return fmt.Errorf("%w: have %d bytes, expected %d", ErrSizeMismatch, stat.Size(), size)
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(path+downloadPartSuffix, path); err != nil {
+		return err
+	}
+	os.Remove(path + downloadStateSuffix)
+	return nil
+}
+
+// do sends a request carrying the Builder headers plus the given options
+func (d *Downloader) do(ctx context.Context, method, url string, opts *HeaderOpts) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	builder := d.Builder
+	if builder == nil {
+		builder = NewBuilder(nil)
+	}
+	if opts == nil {
+		opts = &d.Opts
+	}
+	headers, err := builder.BuildContext(ctx, *opts)
+	if err != nil {
+		return nil, err
+	}
+	applyHeaders(req, headers)
+
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+// loadState returns the saved resume state when it still describes the same
+// resource, or a fresh state otherwise
+func (d *Downloader) loadState(path, url string, info resourceInfo, chunkSize int64) (*downloadState, bool) {
+	fresh := &downloadState{
+		URL:          url,
+		Size:         info.size,
+		ETag:         info.etag,
+		LastModified: info.lastModified,
+		ChunkSize:    chunkSize,
+	}
+
+	data, err := os.ReadFile(path + downloadStateSuffix)
+	if err != nil {
+		return fresh, false
+	}
+	var saved downloadState
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fresh, false
+	}
+
+	// Resuming is only safe with a validator usable in If-Range
+	if ifRangeValidator(saved.ETag, saved.LastModified) == "" {
+		return fresh, false
+	}
+	if saved.URL != url || saved.Size != info.size || saved.ChunkSize != chunkSize ||
+		saved.ETag != info.etag || saved.LastModified != info.lastModified {
+		return fresh, false
+	}
+	if _, err := os.Stat(path + downloadPartSuffix); err != nil {
+		return fresh, false
+	}
+	return &saved, len(saved.Completed) > 0
+}
+
+// saveState persists the resume state next to the partial file
+func (d *Downloader) saveState(path string, state *downloadState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path+downloadStateSuffix, data, 0o644)
+}
+
+// discard removes any partial data and resume state for path
+func (d *Downloader) discard(path string) {
+	os.Remove(path + downloadPartSuffix)
+	os.Remove(path + downloadStateSuffix)
+}
+
+// ifRangeValidator picks the validator to send in If-Range, preferring a
+// strong ETag over Last-Modified
+func ifRangeValidator(etag, lastModified string) string {
+	if v := IfRangeETag(etag); v != "" {
+		return v
+	}
+	if lastModified != "" {
+		if _, err := ParseHTTPDate(lastModified); err == nil {
+			return lastModified
+		}
+	}
+	return ""
+}
@@ -0,0 +1,395 @@
+package headers
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidForwarded is returned for malformed Forwarded headers and node identifiers
+var ErrInvalidForwarded = errors.New("headers: invalid Forwarded header")
+
+// ForwardedNode is an RFC 7239 node identifier: an IP address, "unknown", or an
+// obfuscated identifier such as "_hidden", with an optional (possibly
+// obfuscated) port
+type ForwardedNode struct {
+	Addr netip.Addr // Zero for unknown and obfuscated nodes
+	Name string     // "unknown" or the obfuscated identifier when Addr is zero
+	Port string
+}
+
+// ParseForwardedNode parses a node identifier such as 192.0.2.43,
+// "[2001:db8:cafe::17]:4711", unknown or _hidden:_port
+func ParseForwardedNode(s string) (ForwardedNode, error) {
+	invalid := fmt.Errorf("%w: node %q", ErrInvalidForwarded, s)
+	var node ForwardedNode
+	name := s
+	if strings.HasPrefix(s, "[") {
+		end := strings.IndexByte(s, ']')
+		if end < 0 {
+			return ForwardedNode{}, invalid
+		}
+		addr, err := netip.ParseAddr(s[1:end])
+		if err != nil || !addr.Is6() {
+			return ForwardedNode{}, invalid
+		}
+		node.Addr = addr
+		name = ""
+		if rest := s[end+1:]; rest != "" {
+			port, ok := strings.CutPrefix(rest, ":")
+			if !ok {
+				return ForwardedNode{}, invalid
+			}
+			node.Port = port
+		}
+	} else if host, port, ok := strings.Cut(s, ":"); ok {
+		name, node.Port = host, port
+	}
+
+	if name != "" {
+		switch {
+		case strings.EqualFold(name, "unknown"):
+			node.Name = "unknown"
+		case isObfuscatedIdentifier(name):
+			node.Name = name
+		default:
+			addr, err := netip.ParseAddr(name)
+			if err != nil || !addr.Is4() {
+				return ForwardedNode{}, invalid
+			}
+			node.Addr = addr
+		}
+	}
+	if node.Port != "" && !isForwardedPort(node.Port) {
+		return ForwardedNode{}, invalid
+	}
+	return node, nil
+}
+
+// IsZero reports whether the node is absent
+func (n ForwardedNode) IsZero() bool {
+	return !n.Addr.IsValid() && n.Name == ""
+}
+
+// String formats the node identifier, bracketing IPv6 addresses
+func (n ForwardedNode) String() string {
+	var s string
+	switch {
+	case n.Addr.Is6() && !n.Addr.Is4In6():
+		s = "[" + n.Addr.String() + "]"
+	case n.Addr.IsValid():
+		s = n.Addr.Unmap().String()
+	default:
+		s = n.Name
+	}
+	if n.Port != "" {
+		s += ":" + n.Port
+	}
+	return s
+}
+
+// isObfuscatedIdentifier checks "_" followed by letters, digits, ".", "_" or "-"
+func isObfuscatedIdentifier(s string) bool {
+	if len(s) < 2 || s[0] != '_' {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '.', c == '_', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// isForwardedPort checks a 1-5 digit port or an obfuscated port
+func isForwardedPort(s string) bool {
+	if isObfuscatedIdentifier(s) {
+		return true
+	}
+	if len(s) == 0 || len(s) > 5 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ForwardedElement is one proxy hop of a Forwarded header
+type ForwardedElement struct {
+	For        ForwardedNode
+	By         ForwardedNode
+	Host       string
+	Proto      string
+	Extensions map[string]string // Other parameters, keyed by lowercase name
+}
+
+// String formats the element as for=...;by=...;host=...;proto=..., quoting
+// values that are not tokens, such as IPv6 nodes
+func (e ForwardedElement) String() string {
+	var pairs []string
+	if !e.For.IsZero() {
+		pairs = append(pairs, "for="+quoteIfNeeded(e.For.String()))
+	}
+	if !e.By.IsZero() {
+		pairs = append(pairs, "by="+quoteIfNeeded(e.By.String()))
+	}
+	if e.Host != "" {
+		pairs = append(pairs, "host="+quoteIfNeeded(e.Host))
+	}
+	if e.Proto != "" {
+		pairs = append(pairs, "proto="+quoteIfNeeded(e.Proto))
+	}
+	for _, name := range slices.Sorted(maps.Keys(e.Extensions)) {
+		pairs = append(pairs, name+"="+quoteIfNeeded(e.Extensions[name]))
+	}
+	return strings.Join(pairs, ";")
+}
+
+// FormatForwarded formats elements as a Forwarded header value
+func FormatForwarded(elements ...ForwardedElement) string {
+	parts := make([]string, len(elements))
+	for i, e := range elements {
+		parts[i] = e.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ParseForwarded parses Forwarded header values into elements, ordered from the
+// client towards the last proxy
+func ParseForwarded(values ...string) ([]ForwardedElement, error) {
+	var elements []ForwardedElement
+	for _, value := range values {
+		for _, member := range splitQuotedList(value, ',') {
+			element, err := parseForwardedElement(member)
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, element)
+		}
+	}
+	return elements, nil
+}
+
+// parseForwardedElement parses the ;-separated pairs of one element
+func parseForwardedElement(s string) (ForwardedElement, error) {
+	var e ForwardedElement
+	seen := make(map[string]bool)
+	for _, pair := range splitQuotedList(s, ';') {
+		name, value, ok := strings.Cut(pair, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		value = strings.TrimSpace(value)
+		if !ok || !isToken(name) || seen[name] {
+			return ForwardedElement{}, fmt.Errorf("%w: %q", ErrInvalidForwarded, pair)
+		}
+		seen[name] = true
+		if !strings.HasPrefix(value, `"`) && !isToken(value) {
+			return ForwardedElement{}, fmt.Errorf("%w: %q", ErrInvalidForwarded, pair)
+		}
+		value = unquote(value)
+
+		var err error
+		switch name {
+		case "for":
+			e.For, err = ParseForwardedNode(value)
+		case "by":
+			e.By, err = ParseForwardedNode(value)
+		case "host":
+			e.Host = value
+		case "proto":
+			e.Proto = strings.ToLower(value)
+		default:
+			if e.Extensions == nil {
+				e.Extensions = make(map[string]string)
+			}
+			e.Extensions[name] = value
+		}
+		if err != nil {
+			return ForwardedElement{}, err
+		}
+	}
+	return e, nil
+}
+
+// ParseXForwardedFor parses X-Forwarded-For values, ordered from the client
+// towards the last proxy. Entries are IP addresses, optionally with a port;
+// anything else becomes an unknown node.
+func ParseXForwardedFor(values ...string) []ForwardedNode {
+	var nodes []ForwardedNode
+	for _, value := range values {
+		for entry := range strings.SplitSeq(value, ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			if addr, err := netip.ParseAddr(entry); err == nil {
+				nodes = append(nodes, ForwardedNode{Addr: addr.Unmap()})
+			} else if node, err := ParseForwardedNode(entry); err == nil {
+				nodes = append(nodes, node)
+			} else {
+				nodes = append(nodes, ForwardedNode{Name: "unknown"})
+			}
+		}
+	}
+	return nodes
+}
+
+// splitHeaderList splits comma-separated header values, trimming and optionally
+// lowercasing each entry
+func splitHeaderList(values []string, lower bool) []string {
+	var list []string
+	for _, value := range values {
+		for entry := range strings.SplitSeq(value, ",") {
+			entry = strings.TrimSpace(entry)
+			if lower {
+				entry = strings.ToLower(entry)
+			}
+			if entry != "" {
+				list = append(list, entry)
+			}
+		}
+	}
+	return list
+}
+
+// ClientInfo describes the original client of a proxied request
+type ClientInfo struct {
+	Addr   netip.Addr    // Zero when the client is unknown or obfuscated
+	Node   ForwardedNode // The client's node identifier as reported
+	Scheme string        // "http" or "https" as used by the client
+	Host   string        // Host requested by the client
+}
+
+// ProxyResolver determines the real client of a request that passed through
+// proxies, trusting forwarding headers only when added by trusted proxies
+type ProxyResolver struct {
+	Trusted []netip.Prefix
+}
+
+// NewProxyResolver creates a ProxyResolver trusting the given CIDR ranges or
+// single IP addresses
+func NewProxyResolver(trusted ...string) (*ProxyResolver, error) {
+	r := &ProxyResolver{}
+	for _, s := range trusted {
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, err
+			}
+			r.Trusted = append(r.Trusted, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, err
+		}
+		r.Trusted = append(r.Trusted, prefix.Masked())
+	}
+	return r, nil
+}
+
+// Trusts reports whether an address belongs to a trusted proxy
+func (r *ProxyResolver) Trusts(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range r.Trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedHop is one entry of the forwarding chain with what its proxy reported
+type forwardedHop struct {
+	node  ForwardedNode
+	proto string
+	host  string
+}
+
+// Resolve walks the forwarding chain from the connected peer towards the client,
+// stopping at the first address that is not a trusted proxy. Forwarded is used
+// when present, otherwise X-Forwarded-For with X-Forwarded-Proto and
+// X-Forwarded-Host; when those are not one entry per hop, their last value is
+// used. A malformed chain is ignored and the peer is reported as the client.
+func (r *ProxyResolver) Resolve(req *http.Request) ClientInfo {
+	info := ClientInfo{Scheme: "http", Host: req.Host}
+	if req.TLS != nil {
+		info.Scheme = "https"
+	}
+	info.Addr = peerAddr(req)
+	info.Node = ForwardedNode{Addr: info.Addr}
+	if !info.Addr.IsValid() || !r.Trusts(info.Addr) {
+		return info
+	}
+
+	hops, ok := forwardedHops(req.Header)
+	if !ok {
+		return info
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := hops[i]
+		info.Node = hop.node
+		info.Addr = hop.node.Addr
+		if hop.proto != "" {
+			info.Scheme = hop.proto
+		}
+		if hop.host != "" {
+			info.Host = hop.host
+		}
+		if !hop.node.Addr.IsValid() || !r.Trusts(hop.node.Addr) {
+			break
+		}
+	}
+	return info
+}
+
+// forwardedHops collects the chain from Forwarded or the X-Forwarded-* headers
+func forwardedHops(header http.Header) ([]forwardedHop, bool) {
+	if values := header.Values(HeaderForwarded); len(values) > 0 {
+		elements, err := ParseForwarded(values...)
+		if err != nil {
+			return nil, false
+		}
+		hops := make([]forwardedHop, 0, len(elements))
+		for _, e := range elements {
+			if e.For.IsZero() {
+				return nil, false
+			}
+			hops = append(hops, forwardedHop{node: e.For, proto: e.Proto, host: e.Host})
+		}
+		return hops, true
+	}
+
+	nodes := ParseXForwardedFor(header.Values(HeaderXForwardedFor)...)
+	if len(nodes) == 0 {
+		return nil, false
+	}
+	protos := splitHeaderList(header.Values(HeaderXForwardedProto), true)
+	hosts := splitHeaderList(header.Values(HeaderXForwardedHost), false)
+	hops := make([]forwardedHop, len(nodes))
+	for i, node := range nodes {
+		hops[i] = forwardedHop{node: node, proto: listEntry(protos, i, len(nodes)), host: listEntry(hosts, i, len(nodes))}
+	}
+	return hops, true
+}
+
+// listEntry returns the entry for hop i of n when the list has one entry per
+// hop, and otherwise the last entry
+func listEntry(list []string, i, n int) string {
+	switch {
+	case len(list) == 0:
+		return ""
+	case len(list) == n:
+		return list[i]
+	}
+	return list[len(list)-1]
+}
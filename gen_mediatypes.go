@@ -0,0 +1,130 @@
+//go:build ignore
+
+// gen_mediatypes regenerates mediatypes.txt from the mime-db database, which
+// collects the IANA media type registry together with Apache and nginx
+// extension mappings and compressibility flags.
+//
+// Usage:
+//
+//	go run gen_mediatypes.go [-db path-or-url] [-out mediatypes.txt]
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+const mimeDBURL = "https://cdn.jsdelivr.net/gh/jshttp/mime-db@master/db.json"
+
+// entry mirrors a mime-db record
+type entry struct {
+	Source       string   `json:"source"`
+	Compressible *bool    `json:"compressible"`
+	Extensions   []string `json:"extensions"`
+}
+
+// supplements are registered types missing from older mime-db snapshots, and
+// preferences from newer RFCs (RFC 9239 makes text/javascript canonical)
+var supplements = map[string]entry{
+	"text/javascript":     {Source: "override", Compressible: ptr(true), Extensions: []string{"js", "mjs"}},
+	"image/avif":          {Source: "override", Compressible: ptr(false), Extensions: []string{"avif"}},
+	"image/avif-sequence": {Source: "override", Compressible: ptr(false), Extensions: []string{"avifs"}},
+	"image/jxl":           {Source: "override", Compressible: ptr(false), Extensions: []string{"jxl"}},
+	"image/heic":          {Source: "override", Compressible: ptr(false), Extensions: []string{"heic"}},
+	"image/heif":          {Source: "override", Compressible: ptr(false), Extensions: []string{"heif"}},
+	"image/webp":          {Source: "override", Compressible: ptr(false), Extensions: []string{"webp"}},
+	"application/zstd":    {Source: "override", Compressible: ptr(false), Extensions: []string{"zst"}},
+	"font/woff2":          {Source: "override", Compressible: ptr(false), Extensions: []string{"woff2"}},
+	"application/wasm":    {Source: "override", Compressible: ptr(true), Extensions: []string{"wasm"}},
+}
+
+// sourceRank orders sources so that preferred mappings come first
+var sourceRank = map[string]int{"override": 0, "iana": 1, "apache": 2, "nginx": 3, "none": 4}
+
+func main() {
+	db := flag.String("db", mimeDBURL, "mime-db db.json path or URL")
+	out := flag.String("out", "mediatypes.txt", "output file")
+	flag.Parse()
+
+	data, err := load(*db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var entries map[string]entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		log.Fatal(err)
+	}
+	for name, e := range supplements {
+		entries[name] = e
+	}
+
+	names := make([]string, 0, len(entries))
+	for name, e := range entries {
+		if len(e.Extensions) == 0 && e.Compressible == nil {
+			continue
+		}
+		if e.Source == "" {
+			entries[name] = entry{Source: "none", Compressible: e.Compressible, Extensions: e.Extensions}
+		}
+		names = append(names, name)
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		if r := sourceRank[entries[a].Source] - sourceRank[entries[b].Source]; r != 0 {
+			return r
+		}
+		return strings.Compare(a, b)
+	})
+
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := bufio.NewWriter(f)
+	fmt.Fprintln(w, "# Code generated by gen_mediatypes.go; DO NOT EDIT.")
+	fmt.Fprintln(w, "# media-type source compressible(1/0/?) extensions")
+	for _, name := range names {
+		e := entries[name]
+		compressible := "?"
+		if e.Compressible != nil {
+			compressible = map[bool]string{true: "1", false: "0"}[*e.Compressible]
+		}
+		exts := strings.Join(e.Extensions, ",")
+		if exts == "" {
+			exts = "-"
+		}
+		fmt.Fprintf(w, "%s %s %s %s\n", name, e.Source, compressible, exts)
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func load(src string) ([]byte, error) {
+	if !strings.HasPrefix(src, "http://") && !strings.HasPrefix(src, "https://") {
+		return os.ReadFile(src)
+	}
+	resp, err := http.Get(src)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", src, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
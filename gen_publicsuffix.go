@@ -0,0 +1,94 @@
+//go:build ignore
+
+// gen_publicsuffix regenerates publicsuffix.txt from the Public Suffix List,
+// keeping each rule with the section (ICANN or private) it was listed in.
+// IDN rules stay in Unicode, as published.
+//
+// Usage:
+//
+//	go run gen_publicsuffix.go [-list path-or-url] [-out publicsuffix.txt]
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const publicSuffixURL = "https://publicsuffix.org/list/public_suffix_list.dat"
+
+func main() {
+	list := flag.String("list", publicSuffixURL, "public_suffix_list.dat path or URL")
+	out := flag.String("out", "publicsuffix.txt", "output file")
+	flag.Parse()
+
+	data, err := load(*list)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := bufio.NewWriter(f)
+	fmt.Fprintln(w, "# Code generated by gen_publicsuffix.go; DO NOT EDIT.")
+	fmt.Fprintln(w, "# Source: https://publicsuffix.org/list/ (MPL 2.0)")
+	fmt.Fprintln(w, "# rule section(i=ICANN/p=private)")
+
+	section, rules := "", 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.Contains(line, "===BEGIN ICANN DOMAINS==="):
+			section = "i"
+			continue
+		case strings.Contains(line, "===BEGIN PRIVATE DOMAINS==="):
+			section = "p"
+			continue
+		case strings.Contains(line, "===END "):
+			section = ""
+			continue
+		case line == "", strings.HasPrefix(line, "//"), section == "":
+			continue
+		}
+		// Rules end at the first whitespace
+		rule := strings.ToLower(strings.Fields(line)[0])
+		fmt.Fprintf(w, "%s %s\n", rule, section)
+		rules++
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatal(err)
+	}
+	if rules == 0 {
+		log.Fatal("no rules found; is the input a public suffix list?")
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func load(src string) ([]byte, error) {
+	if !strings.HasPrefix(src, "http://") && !strings.HasPrefix(src, "https://") {
+		return os.ReadFile(src)
+	}
+	resp, err := http.Get(src)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", src, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
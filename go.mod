@@ -1,3 +1,8 @@
 module github.com/broaskaGit/headers
 
 go 1.24.5
+
+require (
+	github.com/andybalholm/brotli v1.2.0
+	github.com/klauspost/compress v1.18.0
+)
@@ -1,50 +1,66 @@
 package headers
 
 import (
+	"context"
 	"maps"
+	"net/http"
+	"slices"
+	"strings"
 	"sync"
 )
 
 // HeaderOpts contains options for building headers using custom types
 type HeaderOpts struct {
-	ContentType               ContentType
-	Accept                    Accept
-	AcceptLanguage            AcceptLanguage
-	AcceptEncoding            AcceptEncoding
-	Connection                Connection
-	UserAgent                 UserAgent
-	Referer                   string
-	Origin                    string
-	Host                      string
-	Authorization             Authorization
-	CacheControl              CacheControl
-	Pragma                    Pragma
-	DNT                       DNT
-	SecFetchDest              SecFetchDest
-	SecFetchMode              SecFetchMode
-	SecFetchSite              SecFetchSite
-	SecFetchUser              SecFetchUser
-	XRequestedWith            XRequestedWith
-	XFrameOptions             XFrameOptions
-	XContentTypeOptions       XContentTypeOptions
-	XCSRFToken                string
-	StrictTransportSecurity   StrictTransportSecurity
-	ContentSecurityPolicy     string
-	AccessControlAllowOrigin  AccessControlAllowOrigin
-	AccessControlAllowMethods AccessControlAllowMethods
-	AccessControlAllowHeaders AccessControlAllowHeaders
-	Range                     Range
-	IfModifiedSince           string
-	IfNoneMatch               string
-	ContentDisposition        ContentDisposition
-	Custom                    map[string]string
-	IncludeSecUserAgent       bool // Include Sec-CH-* headers
+	ContentType                    ContentType
+	Accept                         Accept
+	AcceptLanguage                 AcceptLanguage
+	AcceptEncoding                 AcceptEncoding
+	Connection                     Connection
+	UserAgent                      UserAgent
+	Referer                        string
+	Origin                         string
+	Host                           string
+	Authorization                  Authorization
+	CacheControl                   CacheControl
+	Pragma                         Pragma
+	DNT                            DNT
+	SecFetchDest                   SecFetchDest
+	SecFetchMode                   SecFetchMode
+	SecFetchSite                   SecFetchSite
+	SecFetchUser                   SecFetchUser
+	UpgradeInsecureRequests        bool
+	DisableUpgradeInsecureRequests bool // Omits Upgrade-Insecure-Requests, even when set by a preset or Session
+	Priority                       Priority
+	XRequestedWith                 XRequestedWith
+	XFrameOptions                  XFrameOptions
+	XContentTypeOptions            XContentTypeOptions
+	XCSRFToken                     string
+	StrictTransportSecurity        StrictTransportSecurity
+	ContentSecurityPolicy          string
+	AccessControlAllowOrigin       AccessControlAllowOrigin
+	AccessControlAllowMethods      AccessControlAllowMethods
+	AccessControlAllowHeaders      AccessControlAllowHeaders
+	Range                          Range
+	IfRange                        string
+	IfModifiedSince                string
+	IfUnmodifiedSince              string
+	IfNoneMatch                    string
+	IfMatch                        string
+	ContentDisposition             ContentDisposition
+	Custom                         map[string]string
+	CustomHeaders                  *Headers                 // Multi-value custom headers, applied after Custom
+	Merge                          map[string]MergeStrategy // Per-build merge strategies, overriding the Builder's
+	Remove                         []string                 // Headers deleted from the result, including basic headers
+	IncludeSecUserAgent            bool                     // Include Sec-CH-* headers
+	Profile                        Profile                  // Browser whose per-destination Accept values fill an Accept unset by opt and the Builder
 }
 
 // Builder provides a reusable builder for basic headers
 type Builder struct {
 	mu           sync.RWMutex
 	basicHeaders map[string]string
+	strategies   map[string]MergeStrategy // Canonical header name -> strategy
+	providers    map[string]HeaderProvider
 }
 
 // OptionsBuilder provides a fluent interface for building HeaderOpts
@@ -85,137 +101,289 @@ func (hb *Builder) RemoveBasicHeader(key string) *Builder {
 	return hb
 }
 
-// Build constructs the final headers map, merging basic headers with HeaderOpts
+// setsHeader reports whether a basic header or provider of the Builder supplies name
+func (hb *Builder) setsHeader(name string) bool {
+	hb.mu.RLock()
+	defer hb.mu.RUnlock()
+
+	if hasHeaderFold(hb.basicHeaders, name) {
+		return true
+	}
+	for key := range hb.providers {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasHeaderFold reports whether a header map has name, ignoring case
+func hasHeaderFold(headers map[string]string, name string) bool {
+	for key := range headers {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Build constructs the final headers map, merging basic headers with HeaderOpts.
+// Repeated values are comma-joined; see BuildHeaders for the precedence order.
+// Providers run with a background context, and headers whose provider fails
+// are left out; use BuildContext to receive the error instead.
 func (hb *Builder) Build(opt HeaderOpts) map[string]string {
-	headers := make(map[string]string)
+	return hb.BuildHeaders(opt).Map()
+}
+
+// BuildContext is like Build but runs header providers with ctx and returns
+// the first provider error
+func (hb *Builder) BuildContext(ctx context.Context, opt HeaderOpts) (map[string]string, error) {
+	headers, err := hb.buildHeaders(ctx, opt, true)
+	if err != nil {
+		return nil, err
+	}
+	return headers.Map(), nil
+}
+
+// BuildHeaders constructs the final headers as a Headers collection, which keeps
+// repeated values from CustomHeaders. Layers are applied in this order, each
+// merged into the result of the previous ones:
+//
+//  1. basic headers
+//  2. header providers
+//  3. typed options, including the Accept filled from SecFetchDest when no
+//     earlier layer set one
+//  4. client hints, when IncludeSecUserAgent is set
+//  5. Custom
+//  6. CustomHeaders
+//
+// A header set by a later layer replaces the earlier value unless a different
+// MergeStrategy is configured for it, on the Builder or in opt.Merge. Names are
+// matched case-insensitively. Finally, every header named in opt.Remove is deleted.
+// As with Build, failing providers are skipped.
+func (hb *Builder) BuildHeaders(opt HeaderOpts) *Headers {
+	headers, _ := hb.buildHeaders(context.Background(), opt, false)
+	return headers
+}
+
+// BuildHeadersContext is like BuildHeaders but runs header providers with ctx
+// and returns the first provider error
+func (hb *Builder) BuildHeadersContext(ctx context.Context, opt HeaderOpts) (*Headers, error) {
+	return hb.buildHeaders(ctx, opt, true)
+}
+
+// buildHeaders implements the build variants; strict makes provider errors fatal
+func (hb *Builder) buildHeaders(ctx context.Context, opt HeaderOpts, strict bool) (*Headers, error) {
+	hb.mu.RLock()
+	strategies := maps.Clone(hb.strategies)
+	providers := maps.Clone(hb.providers)
+	hb.mu.RUnlock()
+	for name, strategy := range opt.Merge {
+		if strategies == nil {
+			strategies = make(map[string]MergeStrategy)
+		}
+		strategies[http.CanonicalHeaderKey(name)] = strategy
+	}
+
+	provided, err := runProviders(ctx, providers, strict)
+	if err != nil {
+		return nil, err
+	}
 
+	headers := &Headers{}
+	merge := func(name string, values ...string) {
+		mergeHeader(headers, strategies[http.CanonicalHeaderKey(name)], name, values)
+	}
+	hb.build(opt, provided, func(name, value string) {
+		merge(name, value)
+	})
+	for name, values := range opt.CustomHeaders.Fields() {
+		merge(name, values...)
+	}
+	for _, name := range opt.Remove {
+		headers.Del(name)
+	}
+	return headers, nil
+}
+
+// build emits basic headers in name order, then provided values, then the
+// options, through set
+func (hb *Builder) build(opt HeaderOpts, provided *Headers, set func(name, value string)) {
 	// Start with basic headers
 	hb.mu.RLock()
-	maps.Copy(headers, hb.basicHeaders)
+	basics := maps.Clone(hb.basicHeaders)
 	hb.mu.RUnlock()
+	for _, name := range slices.Sorted(maps.Keys(basics)) {
+		set(name, basics[name])
+	}
+
+	// Then values computed for this build
+	for name, value := range provided.All() {
+		set(name, value)
+	}
+	earlier := func(name string) bool {
+		return hasHeaderFold(basics, name) || provided.Has(name)
+	}
 
 	// Using provided options
 
 	// Override with specific options (only if they're set)
 	if opt.Accept != "" {
-		headers["Accept"] = string(opt.Accept)
+		set("Accept", string(opt.Accept))
+	} else if opt.SecFetchDest != "" && !earlier("Accept") {
+		accept, encoding := DestinationAccept(opt.Profile, opt.SecFetchDest)
+		if accept != "" {
+			set("Accept", string(accept))
+		}
+		if encoding != "" && opt.AcceptEncoding == "" && !earlier("Accept-Encoding") {
+			set("Accept-Encoding", string(encoding))
+		}
 	}
 	if opt.AcceptLanguage != "" {
-		headers["Accept-Language"] = string(opt.AcceptLanguage)
+		set("Accept-Language", string(opt.AcceptLanguage))
 	}
 	if opt.AcceptEncoding != "" {
-		headers["Accept-Encoding"] = string(opt.AcceptEncoding)
+		set("Accept-Encoding", string(opt.AcceptEncoding))
 	}
 	if opt.Connection != "" {
-		headers["Connection"] = string(opt.Connection)
+		set("Connection", string(opt.Connection))
 	}
 	if opt.UserAgent != "" {
-		headers["User-Agent"] = string(opt.UserAgent)
+		set("User-Agent", string(opt.UserAgent))
 	}
 
 	// Add optional headers
 	if opt.ContentType != "" {
-		headers["Content-Type"] = string(opt.ContentType)
+		set("Content-Type", string(opt.ContentType))
 	}
 	if opt.Referer != "" {
-		headers["Referer"] = opt.Referer
+		set("Referer", opt.Referer)
 	}
 	if opt.Origin != "" {
-		headers["Origin"] = opt.Origin
+		set("Origin", opt.Origin)
 	}
 	if opt.Host != "" {
-		headers["Host"] = opt.Host
+		set("Host", opt.Host)
 	}
 	if opt.Authorization != "" {
-		headers["Authorization"] = string(opt.Authorization)
+		set("Authorization", string(opt.Authorization))
 	}
 	if opt.CacheControl != "" {
-		headers["Cache-Control"] = string(opt.CacheControl)
+		set("Cache-Control", string(opt.CacheControl))
 	}
 	if opt.Pragma != "" {
-		headers["Pragma"] = string(opt.Pragma)
+		set("Pragma", string(opt.Pragma))
 	}
 	if opt.DNT != "" {
-		headers["DNT"] = string(opt.DNT)
+		set("DNT", string(opt.DNT))
 	}
 	if opt.SecFetchDest != "" {
-		headers["Sec-Fetch-Dest"] = string(opt.SecFetchDest)
+		set("Sec-Fetch-Dest", string(opt.SecFetchDest))
 	}
 	if opt.SecFetchMode != "" {
-		headers["Sec-Fetch-Mode"] = string(opt.SecFetchMode)
+		set("Sec-Fetch-Mode", string(opt.SecFetchMode))
 	}
 	if opt.SecFetchSite != "" {
-		headers["Sec-Fetch-Site"] = string(opt.SecFetchSite)
+		set("Sec-Fetch-Site", string(opt.SecFetchSite))
 	}
 	if opt.SecFetchUser != "" {
-		headers["Sec-Fetch-User"] = string(opt.SecFetchUser)
+		set("Sec-Fetch-User", string(opt.SecFetchUser))
+	}
+	if opt.UpgradeInsecureRequests && !opt.DisableUpgradeInsecureRequests {
+		set("Upgrade-Insecure-Requests", "1")
+	}
+	if opt.Priority != "" {
+		set("Priority", string(opt.Priority))
 	}
 	if opt.XRequestedWith != "" {
-		headers["X-Requested-With"] = string(opt.XRequestedWith)
+		set("X-Requested-With", string(opt.XRequestedWith))
 	}
 	if opt.Range != "" {
-		headers["Range"] = string(opt.Range)
+		set("Range", string(opt.Range))
+	}
+	if opt.IfRange != "" {
+		set("If-Range", opt.IfRange)
 	}
 	if opt.IfModifiedSince != "" {
-		headers["If-Modified-Since"] = opt.IfModifiedSince
+		set("If-Modified-Since", opt.IfModifiedSince)
+	}
+	if opt.IfUnmodifiedSince != "" {
+		set("If-Unmodified-Since", opt.IfUnmodifiedSince)
 	}
 	if opt.IfNoneMatch != "" {
-		headers["If-None-Match"] = opt.IfNoneMatch
+		set("If-None-Match", opt.IfNoneMatch)
+	}
+	if opt.IfMatch != "" {
+		set("If-Match", opt.IfMatch)
 	}
 	if opt.ContentDisposition != "" {
-		headers["Content-Disposition"] = string(opt.ContentDisposition)
+		set("Content-Disposition", string(opt.ContentDisposition))
 	}
 
 	// Security headers
 	if opt.XFrameOptions != "" {
-		headers["X-Frame-Options"] = string(opt.XFrameOptions)
+		set("X-Frame-Options", string(opt.XFrameOptions))
 	}
 	if opt.XContentTypeOptions != "" {
-		headers["X-Content-Type-Options"] = string(opt.XContentTypeOptions)
+		set("X-Content-Type-Options", string(opt.XContentTypeOptions))
 	}
 	if opt.XCSRFToken != "" {
-		headers["X-CSRF-Token"] = opt.XCSRFToken
+		set("X-CSRF-Token", opt.XCSRFToken)
 	}
 	if opt.StrictTransportSecurity != "" {
-		headers["Strict-Transport-Security"] = string(opt.StrictTransportSecurity)
+		set("Strict-Transport-Security", string(opt.StrictTransportSecurity))
 	}
 	if opt.ContentSecurityPolicy != "" {
-		headers["Content-Security-Policy"] = opt.ContentSecurityPolicy
+		set("Content-Security-Policy", opt.ContentSecurityPolicy)
 	}
 
 	// CORS headers
 	if opt.AccessControlAllowOrigin != "" {
-		headers["Access-Control-Allow-Origin"] = string(opt.AccessControlAllowOrigin)
+		set("Access-Control-Allow-Origin", string(opt.AccessControlAllowOrigin))
 	}
 	if opt.AccessControlAllowMethods != "" {
-		headers["Access-Control-Allow-Methods"] = string(opt.AccessControlAllowMethods)
+		set("Access-Control-Allow-Methods", string(opt.AccessControlAllowMethods))
 	}
 	if opt.AccessControlAllowHeaders != "" {
-		headers["Access-Control-Allow-Headers"] = string(opt.AccessControlAllowHeaders)
+		set("Access-Control-Allow-Headers", string(opt.AccessControlAllowHeaders))
 	}
 
 	// Add Sec-CH headers if requested
 	if opt.IncludeSecUserAgent {
-		headers["Sec-CH-UA"] = SecCHUserAgentDefault
-		headers["Sec-CH-UA-Full-Version-List"] = SecCHFullVersionDefault
-		headers["Sec-CH-UA-Platform"] = SecCHPlatformDefault
-		headers["Sec-CH-UA-Platform-Version"] = SecCHPlatformVersionDefault
-		headers["Sec-CH-UA-Mobile"] = SecCHMobileDefault
+		set("Sec-CH-UA", SecCHUserAgentDefault)
+		set("Sec-CH-UA-Full-Version-List", SecCHFullVersionDefault)
+		set("Sec-CH-UA-Platform", SecCHPlatformDefault)
+		set("Sec-CH-UA-Platform-Version", SecCHPlatformVersionDefault)
+		set("Sec-CH-UA-Mobile", SecCHMobileDefault)
 		if SecCHModelDefault != "" {
-			headers["Sec-CH-UA-Model"] = SecCHModelDefault
+			set("Sec-CH-UA-Model", SecCHModelDefault)
 		}
-		headers["Sec-CH-Prefers-Color-Scheme"] = SecCHPrefersColorSchemeDefault
+		set("Sec-CH-Prefers-Color-Scheme", SecCHPrefersColorSchemeDefault)
 	}
 
 	// Add custom headers (these can override anything)
-	if opt.Custom != nil {
-		maps.Copy(headers, opt.Custom)
+	for _, name := range slices.Sorted(maps.Keys(opt.Custom)) {
+		set(name, opt.Custom[name])
 	}
-
-	return headers
 }
 
 func Build(opts HeaderOpts) map[string]string {
 	headers := NewBuilder(nil).Build(opts)
 	return headers
 }
+
+// BuildHeaders builds a Headers collection from options alone
+func BuildHeaders(opts HeaderOpts) *Headers {
+	return NewBuilder(nil).BuildHeaders(opts)
+}
+
+// applyHeaders copies built headers onto an outgoing request, routing Host to req.Host
+func applyHeaders(req *http.Request, headers map[string]string) {
+	for key, value := range headers {
+		if http.CanonicalHeaderKey(key) == "Host" {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(key, value)
+	}
+}
@@ -0,0 +1,153 @@
+package headers
+
+import "strings"
+
+// splitQuotedList splits a header list on sep, ignoring separators inside
+// quoted strings, and drops empty elements
+func splitQuotedList(s string, sep byte) []string {
+	var (
+		parts   []string
+		start   int
+		quoted  bool
+		escaped bool
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case !quoted && c == sep:
+			if part := strings.TrimSpace(s[start:i]); part != "" {
+				parts = append(parts, part)
+			}
+			start = i + 1
+		}
+	}
+	if part := strings.TrimSpace(s[start:]); part != "" {
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// unquote removes surrounding quotes and backslash escapes from a quoted-string
+func unquote(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+	s = s[1 : len(s)-1]
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+// quoteIfNeeded returns s as a token when possible and a quoted-string otherwise
+func quoteIfNeeded(s string) string {
+	if s != "" && isToken(s) {
+		return s
+	}
+	return quote(s)
+}
+
+// quote returns s as a quoted-string
+func quote(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(s[i])
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// isToken reports whether s is a non-empty RFC 9110 token
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isTokenChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isTokenChar reports whether c is a tchar
+func isTokenChar(c byte) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0
+}
+
+// qualityItem is a member of a weighted list such as Accept-Encoding
+type qualityItem struct {
+	value string
+	q     float64
+}
+
+// parseQualityList parses a comma-separated list with optional ;q= weights.
+// Values are lowercased and members with invalid weights are dropped.
+func parseQualityList(s string) []qualityItem {
+	var items []qualityItem
+	for _, member := range splitQuotedList(s, ',') {
+		params := splitQuotedList(member, ';')
+		if len(params) == 0 {
+			continue
+		}
+
+		item := qualityItem{value: strings.ToLower(params[0]), q: 1}
+		valid := true
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(param, "=")
+			if !strings.EqualFold(strings.TrimSpace(name), "q") {
+				continue
+			}
+			q, ok := parseQValue(strings.TrimSpace(value))
+			if !ok {
+				valid = false
+				break
+			}
+			item.q = q
+		}
+		if valid {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseQValue parses an RFC 9110 qvalue: "0" or "1" optionally followed by a
+// dot and up to three digits, which must be zeros after "1"
+func parseQValue(s string) (float64, bool) {
+	whole, frac, _ := strings.Cut(s, ".")
+	if (whole != "0" && whole != "1") || len(frac) > 3 {
+		return 0, false
+	}
+	thousandths := int(whole[0]-'0') * 1000
+	for i, scale := 0, 100; i < len(frac); i, scale = i+1, scale/10 {
+		c := frac[i]
+		if c < '0' || c > '9' || (whole == "1" && c != '0') {
+			return 0, false
+		}
+		thousandths += int(c-'0') * scale
+	}
+	return float64(thousandths) / 1000, true
+}
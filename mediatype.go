@@ -0,0 +1,234 @@
+package headers
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidMediaType is returned for values that are not type/subtype media types
+var ErrInvalidMediaType = errors.New("headers: invalid media type")
+
+// MediaType represents a parsed media type such as "application/problem+json;
+// charset=utf-8". Type and Subtype are lowercase, the Subtype includes any
+// structured syntax suffix, and parameter names are lowercase.
+type MediaType struct {
+	Type    string
+	Subtype string
+	Params  map[string]string
+}
+
+// NewMediaType returns a media type without parameters
+func NewMediaType(typ, subtype string) MediaType {
+	return MediaType{
+		Type:    strings.ToLower(typ),
+		Subtype: strings.ToLower(subtype),
+	}
+}
+
+// ParseContentType parses a Content-Type (or any media type) value
+func ParseContentType(value string) (MediaType, error) {
+	essence, rest, _ := strings.Cut(value, ";")
+	typ, subtype, ok := strings.Cut(strings.TrimSpace(essence), "/")
+	typ, subtype = strings.TrimSpace(typ), strings.TrimSpace(subtype)
+	if !ok || !isToken(typ) || !isToken(subtype) {
+		return MediaType{}, fmt.Errorf("%w: %q", ErrInvalidMediaType, value)
+	}
+
+	m := NewMediaType(typ, subtype)
+	for _, param := range splitQuotedList(rest, ';') {
+		name, val, ok := strings.Cut(param, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !ok || !isToken(name) {
+			return MediaType{}, fmt.Errorf("%w: %q", ErrInvalidMediaType, value)
+		}
+		if m.Params == nil {
+			m.Params = make(map[string]string)
+		}
+		// The first occurrence of a parameter wins
+		if _, seen := m.Params[name]; !seen {
+			m.Params[name] = unquote(strings.TrimSpace(val))
+		}
+	}
+	return m, nil
+}
+
+// Essence returns "type/subtype" without parameters
+func (m MediaType) Essence() string {
+	return m.Type + "/" + m.Subtype
+}
+
+// Suffix returns the structured syntax suffix, e.g. "json" for "application/problem+json"
+func (m MediaType) Suffix() string {
+	if i := strings.LastIndexByte(m.Subtype, '+'); i >= 0 {
+		return m.Subtype[i+1:]
+	}
+	return ""
+}
+
+// Param returns the value of a parameter
+func (m MediaType) Param(name string) string {
+	return m.Params[strings.ToLower(name)]
+}
+
+// Charset returns the lowercase charset parameter
+func (m MediaType) Charset() string {
+	return strings.ToLower(m.Param("charset"))
+}
+
+// WithParam returns a copy of the media type with the parameter set
+func (m MediaType) WithParam(name, value string) MediaType {
+	params := make(map[string]string, len(m.Params)+1)
+	maps.Copy(params, m.Params)
+	params[strings.ToLower(name)] = value
+	m.Params = params
+	return m
+}
+
+// WithoutParams returns a copy of the media type with no parameters
+func (m MediaType) WithoutParams() MediaType {
+	m.Params = nil
+	return m
+}
+
+// WithCharset returns a copy of the media type with the charset parameter set
+func (m MediaType) WithCharset(charset string) MediaType {
+	return m.WithParam("charset", strings.ToLower(charset))
+}
+
+// String formats the media type with parameters in sorted order
+func (m MediaType) String() string {
+	var sb strings.Builder
+	sb.WriteString(m.Essence())
+
+	names := slices.Sorted(maps.Keys(m.Params))
+	for _, name := range names {
+		sb.WriteString("; ")
+		sb.WriteString(name)
+		sb.WriteString("=")
+		sb.WriteString(quoteIfNeeded(m.Params[name]))
+	}
+	return sb.String()
+}
+
+// ContentType returns the media type as a ContentType header value
+func (m MediaType) ContentType() ContentType {
+	return ContentType(m.String())
+}
+
+// Equal reports whether two media types are the same, ignoring parameter order
+// and case-insensitive parts (type, subtype, parameter names and charset values)
+func (m MediaType) Equal(other MediaType) bool {
+	if !strings.EqualFold(m.Type, other.Type) || !strings.EqualFold(m.Subtype, other.Subtype) {
+		return false
+	}
+	if len(m.Params) != len(other.Params) {
+		return false
+	}
+	for name, value := range m.Params {
+		otherValue, ok := other.Params[name]
+		if !ok {
+			return false
+		}
+		if name == "charset" {
+			if !strings.EqualFold(value, otherValue) {
+				return false
+			}
+		} else if value != otherValue {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches reports whether the media type falls within a media range such as
+// "image/*" or "*/*"; parameters of the range must be present with equal values
+func (m MediaType) Matches(mediaRange MediaType) bool {
+	if mediaRange.Type != "*" && mediaRange.Type != m.Type {
+		return false
+	}
+	if mediaRange.Subtype != "*" && mediaRange.Subtype != m.Subtype {
+		return false
+	}
+	for name, value := range mediaRange.Params {
+		if name == "q" {
+			continue
+		}
+		if !strings.EqualFold(m.Params[name], value) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsJSON reports whether the media type is JSON, including application/problem+json
+// and vendor types with a +json suffix
+func (m MediaType) IsJSON() bool {
+	return m.Essence() == "application/json" || m.Essence() == "text/json" || m.Suffix() == "json"
+}
+
+// IsXML reports whether the media type is XML, including +xml suffixed types
+func (m MediaType) IsXML() bool {
+	return m.Essence() == "application/xml" || m.Essence() == "text/xml" || m.Suffix() == "xml"
+}
+
+// IsText reports whether the media type is textual
+func (m MediaType) IsText() bool {
+	return m.Type == "text" || m.IsJSON() || m.IsXML() ||
+		m.Essence() == "application/javascript" || m.Essence() == "application/x-www-form-urlencoded"
+}
+
+// IsForm reports whether the media type is URL-encoded or multipart form data
+func (m MediaType) IsForm() bool {
+	return m.Essence() == "application/x-www-form-urlencoded" || m.Essence() == "multipart/form-data"
+}
+
+// IsMultipart reports whether the media type is any multipart type
+func (m MediaType) IsMultipart() bool {
+	return m.Type == "multipart"
+}
+
+// Parse parses the Content-Type value into a MediaType
+func (c ContentType) Parse() (MediaType, error) {
+	return ParseContentType(string(c))
+}
+
+// WithCharset returns the content type with its charset parameter set, e.g.
+// ContentTypeJSON.WithCharset("utf-8"); invalid values are returned unchanged
+func (c ContentType) WithCharset(charset string) ContentType {
+	m, err := c.Parse()
+	if err != nil {
+		return c
+	}
+	return m.WithCharset(charset).ContentType()
+}
+
+// Essence returns the content type without parameters
+func (c ContentType) Essence() ContentType {
+	m, err := c.Parse()
+	if err != nil {
+		return c
+	}
+	return ContentType(m.Essence())
+}
+
+// IsJSON reports whether the content type is a JSON type
+func (c ContentType) IsJSON() bool {
+	m, err := c.Parse()
+	return err == nil && m.IsJSON()
+}
+
+// IsXML reports whether the content type is an XML type
+func (c ContentType) IsXML() bool {
+	m, err := c.Parse()
+	return err == nil && m.IsXML()
+}
+
+// Equal reports whether two content types are equivalent
+func (c ContentType) Equal(other ContentType) bool {
+	a, errA := c.Parse()
+	b, errB := other.Parse()
+	return errA == nil && errB == nil && a.Equal(b)
+}
@@ -0,0 +1,66 @@
+package headers
+
+import (
+	"net/http"
+	"strings"
+)
+
+// MergeStrategy decides how a header set by a later build layer combines with
+// a value from an earlier one
+type MergeStrategy int
+
+// Merge strategies
+const (
+	// MergeOverride replaces the earlier value (the default)
+	MergeOverride MergeStrategy = iota
+	// MergeAppend comma-joins the later value after the earlier one, for list
+	// headers such as Accept-Encoding or Via
+	MergeAppend
+	// MergeKeepFirst keeps the earlier value and ignores later ones
+	MergeKeepFirst
+)
+
+// SetMergeStrategy configures how a header is merged across build layers for
+// every build; MergeOverride restores the default
+func (hb *Builder) SetMergeStrategy(name string, strategy MergeStrategy) *Builder {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+
+	name = http.CanonicalHeaderKey(name)
+	if strategy == MergeOverride {
+		delete(hb.strategies, name)
+		return hb
+	}
+	if hb.strategies == nil {
+		hb.strategies = make(map[string]MergeStrategy)
+	}
+	hb.strategies[name] = strategy
+	return hb
+}
+
+// mergeHeader merges values into headers according to the strategy
+func mergeHeader(headers *Headers, strategy MergeStrategy, name string, values []string) {
+	if len(values) == 0 {
+		return
+	}
+	if !headers.Has(name) {
+		for _, value := range values {
+			headers.Add(name, value)
+		}
+		return
+	}
+
+	switch strategy {
+	case MergeKeepFirst:
+		return
+	case MergeAppend:
+		joined := append(headers.Values(name), values...)
+		headers.Set(name, strings.Join(joined, ", "))
+	default:
+		// Set keeps the header in its original position
+		headers.Set(name, values[0])
+		for _, value := range values[1:] {
+			headers.Add(name, value)
+		}
+	}
+}
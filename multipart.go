@@ -0,0 +1,163 @@
+package headers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"path"
+	"strings"
+)
+
+// Boundary prefixes used by browser engines for multipart/form-data bodies
+const (
+	BoundaryPrefixWebKit = "----WebKitFormBoundary"
+	BoundaryPrefixGecko  = "----geckoformboundary"
+)
+
+// boundaryAlphabet is the character set of WebKit and Blink boundaries
+const boundaryAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// MultipartBuilder builds a streaming multipart/form-data request body with
+// browser-like boundaries and part headers. The body is produced lazily by
+// Reader; file parts are only read ahead to sniff a missing content type.
+type MultipartBuilder struct {
+	boundary string
+	parts    []multipartPart
+}
+
+// multipartPart is a single part with its serialized headers
+type multipartPart struct {
+	header string
+	body   io.Reader
+	size   int64 // -1 when unknown
+}
+
+// NewMultipartBuilder creates a MultipartBuilder using the boundary style of the
+// given browser profile; unknown profiles use the Chrome style
+func NewMultipartBuilder(profile Profile) *MultipartBuilder {
+	return &MultipartBuilder{boundary: NewBoundary(profile)}
+}
+
+// NewBoundary generates a multipart boundary the way the profile's browser does:
+// "----WebKitFormBoundary" plus 16 alphanumerics for Chrome, Edge and Safari, and
+// "----geckoformboundary" plus 32 hex digits for Firefox
+func NewBoundary(profile Profile) string {
+	if profile == ProfileFirefox {
+		b := make([]byte, 16)
+		rand.Read(b)
+		return BoundaryPrefixGecko + hex.EncodeToString(b)
+	}
+
+	b := make([]byte, 16)
+	rand.Read(b)
+	for i := range b {
+		b[i] = boundaryAlphabet[int(b[i])%len(boundaryAlphabet)]
+	}
+	return BoundaryPrefixWebKit + string(b)
+}
+
+// Boundary returns the boundary separating the parts
+func (m *MultipartBuilder) Boundary() string {
+	return m.boundary
+}
+
+// ContentType returns the Content-Type to send with the body
+func (m *MultipartBuilder) ContentType() ContentType {
+	return ContentTypeMultipartPrefix + ContentType(m.boundary)
+}
+
+// AddField adds a plain form field
+func (m *MultipartBuilder) AddField(name, value string) *MultipartBuilder {
+	header := "Content-Disposition: " + string(FormDataDisposition(name, "")) + "\r\n"
+	m.parts = append(m.parts, multipartPart{
+		header: header,
+		body:   strings.NewReader(value),
+		size:   int64(len(value)),
+	})
+	return m
+}
+
+// AddFile adds a file part read from r. Pass a size of -1 when the length is
+// unknown, which makes ContentLength unknown too. An empty contentType is looked
+// up from the filename extension or, failing that, sniffed from the first bytes
+// of r, defaulting to application/octet-stream as browsers do.
+func (m *MultipartBuilder) AddFile(field, filename string, contentType ContentType, r io.Reader, size int64) *MultipartBuilder {
+	if contentType == "" {
+		detected, body, err := peekContentType(filename, r)
+		if err != nil {
+			detected, body = ContentTypeApplicationOctet, errReader{err}
+		}
+		contentType, r = detected, body
+	}
+	// Send only the last path component as browsers do; unlike SanitizeFilename
+	// this keeps leading dots, as in .env
+	name := path.Base(strings.ReplaceAll(filename, `\`, "/"))
+	if name == "." || name == "/" {
+		name = ""
+	}
+	disposition := FormDataDisposition(field, name)
+	if name == "" {
+		// Browsers send an empty filename for a file input with no file
+		disposition += `; filename=""`
+	}
+	header := "Content-Disposition: " + string(disposition) + "\r\n" +
+		"Content-Type: " + string(contentType) + "\r\n"
+	m.parts = append(m.parts, multipartPart{
+		header: header,
+		body:   r,
+		size:   size,
+	})
+	return m
+}
+
+// AddFileBytes adds a file part from memory
+func (m *MultipartBuilder) AddFileBytes(field, filename string, contentType ContentType, data []byte) *MultipartBuilder {
+	return m.AddFile(field, filename, contentType, bytes.NewReader(data), int64(len(data)))
+}
+
+// ContentLength returns the exact body length, or -1 when a part has an unknown size
+func (m *MultipartBuilder) ContentLength() int64 {
+	var n int64
+	for _, part := range m.parts {
+		if part.size < 0 {
+			return -1
+		}
+		n += int64(len(m.partPrefix(part))) + part.size + 2
+	}
+	return n + int64(len(m.closing()))
+}
+
+// Reader returns the body as a stream. File readers are consumed, so the
+// body can only be read once.
+func (m *MultipartBuilder) Reader() io.Reader {
+	readers := make([]io.Reader, 0, len(m.parts)*3+1)
+	for _, part := range m.parts {
+		readers = append(readers,
+			strings.NewReader(m.partPrefix(part)),
+			part.body,
+			strings.NewReader("\r\n"),
+		)
+	}
+	readers = append(readers, strings.NewReader(m.closing()))
+	return io.MultiReader(readers...)
+}
+
+// partPrefix returns the delimiter and headers preceding a part body
+func (m *MultipartBuilder) partPrefix(part multipartPart) string {
+	return "--" + m.boundary + "\r\n" + part.header + "\r\n"
+}
+
+// closing returns the close delimiter ending the body
+func (m *MultipartBuilder) closing() string {
+	return "--" + m.boundary + "--\r\n"
+}
+
+// errReader fails every read with a stored error
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
@@ -0,0 +1,132 @@
+package headers
+
+// Preset holds the header options a browser sets for one kind of request.
+// Applying a preset only fills options that are still unset, so values chosen
+// by the caller always win; set DisableUpgradeInsecureRequests to opt out of
+// Upgrade-Insecure-Requests. Accept is normally left empty so that Build fills it
+// from the destination for the caller's Profile.
+type Preset struct {
+	Name                    string
+	Accept                  Accept
+	SecFetchDest            SecFetchDest
+	SecFetchMode            SecFetchMode
+	SecFetchSite            SecFetchSite
+	SecFetchUser            SecFetchUser
+	XRequestedWith          XRequestedWith
+	Priority                Priority
+	UpgradeInsecureRequests bool
+}
+
+// Request-kind presets, modeled on Chrome. Navigation assumes a URL typed into
+// the address bar; the others assume a same-origin request from the page.
+var (
+	PresetNavigation = Preset{
+		Name:                    "navigation",
+		SecFetchDest:            SecFetchDestDocument,
+		SecFetchMode:            SecFetchModeNavigate,
+		SecFetchSite:            SecFetchSiteNone,
+		SecFetchUser:            SecFetchUserTrue,
+		Priority:                PriorityHighestIncremental,
+		UpgradeInsecureRequests: true,
+	}
+	PresetXHR = Preset{
+		Name:           "xhr",
+		Accept:         AcceptAll,
+		SecFetchDest:   SecFetchDestEmpty,
+		SecFetchMode:   SecFetchModeCORS,
+		SecFetchSite:   SecFetchSiteSameOrigin,
+		XRequestedWith: XRequestedWithXMLHttpRequest,
+		Priority:       PriorityMediumIncremental,
+	}
+	PresetFetch = Preset{
+		Name:         "fetch",
+		SecFetchDest: SecFetchDestEmpty,
+		SecFetchMode: SecFetchModeCORS,
+		SecFetchSite: SecFetchSiteSameOrigin,
+		Priority:     PriorityMediumIncremental,
+	}
+	PresetImage = Preset{
+		Name:         "image",
+		SecFetchDest: SecFetchDestImage,
+		SecFetchMode: SecFetchModeNoCORS,
+		SecFetchSite: SecFetchSiteSameOrigin,
+		Priority:     PriorityLowestIncremental,
+	}
+	PresetScript = Preset{
+		Name:         "script",
+		SecFetchDest: SecFetchDestScript,
+		SecFetchMode: SecFetchModeNoCORS,
+		SecFetchSite: SecFetchSiteSameOrigin,
+		Priority:     PriorityMedium,
+	}
+	PresetStylesheet = Preset{
+		Name:         "stylesheet",
+		SecFetchDest: SecFetchDestStyle,
+		SecFetchMode: SecFetchModeNoCORS,
+		SecFetchSite: SecFetchSiteSameOrigin,
+		Priority:     PriorityHighest,
+	}
+	PresetFont = Preset{
+		Name:         "font",
+		SecFetchDest: SecFetchDestFont,
+		SecFetchMode: SecFetchModeCORS,
+		SecFetchSite: SecFetchSiteSameOrigin,
+		Priority:     PriorityHighest,
+	}
+)
+
+// Apply fills the options the preset covers that are still unset in opts
+func (p Preset) Apply(opts *HeaderOpts) {
+	if opts.Accept == "" {
+		opts.Accept = p.Accept
+	}
+	if opts.SecFetchDest == "" {
+		opts.SecFetchDest = p.SecFetchDest
+	}
+	if opts.SecFetchMode == "" {
+		opts.SecFetchMode = p.SecFetchMode
+	}
+	if opts.SecFetchSite == "" {
+		opts.SecFetchSite = p.SecFetchSite
+	}
+	if opts.SecFetchUser == "" {
+		opts.SecFetchUser = p.SecFetchUser
+	}
+	if opts.XRequestedWith == "" {
+		opts.XRequestedWith = p.XRequestedWith
+	}
+	if opts.Priority == "" {
+		opts.Priority = p.Priority
+	}
+	if p.UpgradeInsecureRequests && !opts.DisableUpgradeInsecureRequests {
+		opts.UpgradeInsecureRequests = true
+	}
+}
+
+// BuildPreset builds headers for a request of the preset's kind. The preset
+// only fills headers that neither opt nor the Builder's basic headers and
+// providers set, so the caller's values always win.
+func (hb *Builder) BuildPreset(preset Preset, opt HeaderOpts) map[string]string {
+	fields := []struct {
+		name  string
+		value *string
+	}{
+		{"Accept", (*string)(&preset.Accept)},
+		{"Sec-Fetch-Dest", (*string)(&preset.SecFetchDest)},
+		{"Sec-Fetch-Mode", (*string)(&preset.SecFetchMode)},
+		{"Sec-Fetch-Site", (*string)(&preset.SecFetchSite)},
+		{"Sec-Fetch-User", (*string)(&preset.SecFetchUser)},
+		{"X-Requested-With", (*string)(&preset.XRequestedWith)},
+		{"Priority", (*string)(&preset.Priority)},
+	}
+	for _, field := range fields {
+		if hb.setsHeader(field.name) {
+			*field.value = ""
+		}
+	}
+	if hb.setsHeader("Upgrade-Insecure-Requests") {
+		preset.UpgradeInsecureRequests = false
+	}
+	preset.Apply(&opt)
+	return hb.Build(opt)
+}
@@ -0,0 +1,90 @@
+package headers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// ErrHeaderProvider wraps errors returned by header providers
+var ErrHeaderProvider = errors.New("headers: header provider failed")
+
+// HeaderProvider computes a header value for each build, for values such as
+// timestamps, request IDs, nonces or rotating tokens. An empty value omits the
+// header from that build.
+type HeaderProvider func(ctx context.Context) (string, error)
+
+// SetProvider registers a provider evaluated on every build for the header
+func (hb *Builder) SetProvider(name string, provider HeaderProvider) *Builder {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+
+	if hb.providers == nil {
+		hb.providers = make(map[string]HeaderProvider)
+	}
+	hb.providers[name] = provider
+	return hb
+}
+
+// RemoveProvider removes the provider registered for a header
+func (hb *Builder) RemoveProvider(name string) *Builder {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+
+	delete(hb.providers, name)
+	return hb
+}
+
+// runProviders evaluates providers in name order. Unless strict, failing
+// providers are skipped instead of aborting the build.
+func runProviders(ctx context.Context, providers map[string]HeaderProvider, strict bool) (*Headers, error) {
+	provided := &Headers{}
+	for _, name := range slices.Sorted(maps.Keys(providers)) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		value, err := providers[name](ctx)
+		if err != nil {
+			if strict {
+				return nil, fmt.Errorf("%w %q: %w", ErrHeaderProvider, name, err)
+			}
+			continue
+		}
+		if value != "" {
+			provided.Set(name, value)
+		}
+	}
+	return provided, nil
+}
+
+// StaticProvider returns a provider that always yields value
+func StaticProvider(value string) HeaderProvider {
+	return func(context.Context) (string, error) {
+		return value, nil
+	}
+}
+
+// UnixTimestampProvider returns a provider yielding the current Unix time in
+// seconds, as commonly sent in X-Timestamp
+func UnixTimestampProvider() HeaderProvider {
+	return func(context.Context) (string, error) {
+		return strconv.FormatInt(time.Now().Unix(), 10), nil
+	}
+}
+
+// NonceProvider returns a provider yielding size random bytes, hex-encoded
+func NonceProvider(size int) HeaderProvider {
+	return func(context.Context) (string, error) {
+		b := make([]byte, size)
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(b), nil
+	}
+}
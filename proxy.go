@@ -0,0 +1,276 @@
+package headers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httputil"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ViaPseudonymDefault identifies the proxy in Via when no name is configured
+const ViaPseudonymDefault = "proxy"
+
+// hopByHopHeaders are the connection-specific headers a proxy must not forward
+var hopByHopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// proxyClientKey is the context key under which the rewriter stores the
+// client's view of a proxied request for ModifyResponse
+type proxyClientKey struct{}
+
+// RemoveHopByHop deletes hop-by-hop headers from h, including the headers named
+// in Connection
+func RemoveHopByHop(h http.Header) {
+	for _, name := range splitHeaderList(h.Values("Connection"), false) {
+		h.Del(name)
+	}
+	for _, name := range hopByHopHeaders {
+		h.Del(name)
+	}
+}
+
+// ProxyRewriter rewrites requests and responses passing through an
+// httputil.ReverseProxy. Use its Rewrite and ModifyResponse methods as the
+// ReverseProxy hooks, or ReverseProxy to create one.
+type ProxyRewriter struct {
+	// Target is the upstream base URL; incoming paths are joined onto its path
+	Target *url.URL
+	// Resolver decides whether forwarding headers from the connected peer are
+	// kept and which scheme and host the client used; nil trusts no peer
+	Resolver *ProxyResolver
+	// Name identifies this proxy in Via, ViaPseudonymDefault when empty
+	Name string
+	// By is added to Forwarded as by=, omitted when zero
+	By ForwardedNode
+	// XForwarded also sets X-Forwarded-For, X-Forwarded-Host and X-Forwarded-Proto
+	XForwarded bool
+	// PreserveHost sends the client's Host upstream instead of the target's
+	PreserveHost bool
+	// RewriteLocation maps Location and Content-Location URLs pointing at the
+	// target back to the origin the client used
+	RewriteLocation bool
+	// CookieDomain replaces the Domain attribute of Set-Cookie headers when set
+	CookieDomain string
+	// Headers filters the client's headers before forwarding, nil forwards all
+	Headers *Policy
+}
+
+// NewProxyRewriter creates a ProxyRewriter for target that rewrites redirects
+func NewProxyRewriter(target *url.URL) *ProxyRewriter {
+	return &ProxyRewriter{Target: target, RewriteLocation: true}
+}
+
+// ReverseProxy returns an httputil.ReverseProxy using the rewriter's hooks
+func (p *ProxyRewriter) ReverseProxy() *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{Rewrite: p.Rewrite, ModifyResponse: p.ModifyResponse}
+}
+
+// Rewrite routes pr.Out to the target. Hop-by-hop headers are stripped (protocol
+// upgrades and TE: trailers are carried over as ReverseProxy does), the rest
+// are filtered by the Headers policy, the client is appended to Forwarded and
+// Via, and Host, Origin and Referer are rewritten from the client's origin to
+// the target's.
+func (p *ProxyRewriter) Rewrite(pr *httputil.ProxyRequest) {
+	in, out := pr.In, pr.Out
+	resolver := p.Resolver
+	if resolver == nil {
+		resolver = &ProxyResolver{}
+	}
+	client := resolver.Resolve(in)
+	peer := peerAddr(in)
+	trusted := peer.IsValid() && resolver.Trusts(peer)
+
+	// Hop-by-hop headers
+	RemoveHopByHop(out.Header)
+	if p.Headers != nil {
+		out.Header = p.Headers.FilterHTTP(out.Header)
+	}
+	if upgrade := upgradeType(in.Header); upgrade != "" {
+		out.Header.Set("Connection", "Upgrade")
+		out.Header.Set("Upgrade", upgrade)
+	}
+	if slices.Contains(splitHeaderList(in.Header.Values("Te"), true), string(TETrailers)) {
+		out.Header.Set("Te", string(TETrailers))
+	}
+
+	// Forwarding headers
+	scheme := "http"
+	if in.TLS != nil {
+		scheme = "https"
+	}
+	var elements []ForwardedElement
+	if trusted {
+		if hops, ok := forwardedHops(in.Header); ok {
+			for _, hop := range hops {
+				elements = append(elements, ForwardedElement{For: hop.node, Host: hop.host, Proto: hop.proto})
+			}
+		}
+	}
+	hop := ForwardedElement{For: ForwardedNode{Name: "unknown"}, By: p.By, Host: in.Host, Proto: scheme}
+	if peer.IsValid() {
+		hop.For = ForwardedNode{Addr: peer}
+	}
+	out.Header.Set(HeaderForwarded, FormatForwarded(append(elements, hop)...))
+	if p.XForwarded {
+		prior := ""
+		if trusted {
+			prior = strings.Join(in.Header.Values(HeaderXForwardedFor), ", ")
+		}
+		if peer.IsValid() {
+			prior = strings.TrimPrefix(prior+", "+peer.String(), ", ")
+		}
+		if prior != "" {
+			out.Header.Set(HeaderXForwardedFor, prior)
+		}
+		out.Header.Set(HeaderXForwardedHost, client.Host)
+		out.Header.Set(HeaderXForwardedProto, client.Scheme)
+	}
+	out.Header.Add("Via", viaEntry(in.ProtoMajor, in.ProtoMinor, p.name()))
+
+	// Upstream URL and origin
+	pr.SetURL(p.Target)
+	if p.PreserveHost {
+		out.Host = in.Host
+	}
+	public := &url.URL{Scheme: client.Scheme, Host: client.Host}
+	if origin := out.Header.Get("Origin"); origin != "" && origin == serializeOrigin(public) {
+		out.Header.Set("Origin", serializeOrigin(p.Target))
+	}
+	if referer, err := url.Parse(out.Header.Get("Referer")); err == nil && referer.IsAbs() && SameOrigin(referer, public) {
+		referer.Scheme, referer.Host = p.Target.Scheme, p.Target.Host
+		referer.Path, referer.RawPath = joinProxyPath(p.Target.Path, referer.Path), ""
+		out.Header.Set("Referer", referer.String())
+	}
+
+	pr.Out = out.WithContext(context.WithValue(out.Context(), proxyClientKey{}, client))
+}
+
+// ModifyResponse adds this proxy to Via and, when configured, rewrites Location,
+// Content-Location and Set-Cookie Domain for the client
+func (p *ProxyRewriter) ModifyResponse(resp *http.Response) error {
+	resp.Header.Add("Via", viaEntry(resp.ProtoMajor, resp.ProtoMinor, p.name()))
+
+	if p.RewriteLocation && resp.Request != nil {
+		if client, ok := resp.Request.Context().Value(proxyClientKey{}).(ClientInfo); ok {
+			for _, name := range []string{"Location", "Content-Location"} {
+				if value := resp.Header.Get(name); value != "" {
+					resp.Header.Set(name, p.publicLocation(value, resp.Request.URL, client))
+				}
+			}
+		}
+	}
+
+	if p.CookieDomain != "" {
+		cookies := resp.Header.Values("Set-Cookie")
+		for i, cookie := range cookies {
+			cookies[i] = setCookieDomain(cookie, p.CookieDomain)
+		}
+	}
+	return nil
+}
+
+// publicLocation maps a URL on the target, resolved against the upstream
+// request URL, to the client's origin with the target's base path removed.
+// Relative references keep their form; other URLs are returned unchanged.
+func (p *ProxyRewriter) publicLocation(value string, base *url.URL, client ClientInfo) string {
+	loc, err := url.Parse(value)
+	switch {
+	case err != nil:
+		return value
+	case loc.IsAbs() || loc.Host != "":
+		if !SameOrigin(base.ResolveReference(loc), p.Target) {
+			return value
+		}
+	case !strings.HasPrefix(loc.Path, "/"):
+		return value
+	}
+
+	prefix := strings.TrimSuffix(p.Target.Path, "/")
+	path, ok := strings.CutPrefix(loc.Path, prefix)
+	if !ok || (path != "" && !strings.HasPrefix(path, "/")) {
+		return value
+	}
+	if path == "" {
+		path = "/"
+	}
+	loc.Path, loc.RawPath = path, ""
+	if loc.IsAbs() || loc.Host != "" {
+		loc.Scheme, loc.Host = client.Scheme, client.Host
+	}
+	return loc.String()
+}
+
+// name returns the Via pseudonym
+func (p *ProxyRewriter) name() string {
+	if p.Name == "" {
+		return ViaPseudonymDefault
+	}
+	return p.Name
+}
+
+// peerAddr returns the address of the connected peer
+func peerAddr(req *http.Request) netip.Addr {
+	if addrPort, err := netip.ParseAddrPort(req.RemoteAddr); err == nil {
+		return addrPort.Addr().Unmap()
+	}
+	if addr, err := netip.ParseAddr(req.RemoteAddr); err == nil {
+		return addr.Unmap()
+	}
+	return netip.Addr{}
+}
+
+// upgradeType returns the protocol requested in Upgrade when Connection lists it
+func upgradeType(h http.Header) string {
+	if !slices.Contains(splitHeaderList(h.Values("Connection"), true), "upgrade") {
+		return ""
+	}
+	return h.Get("Upgrade")
+}
+
+// viaEntry formats a Via entry such as "1.1 proxy" or "2 proxy"
+func viaEntry(major, minor int, name string) string {
+	version := strconv.Itoa(major)
+	if major < 2 {
+		version += "." + strconv.Itoa(minor)
+	}
+	return version + " " + name
+}
+
+// joinProxyPath joins a target base path and a request path with one slash
+func joinProxyPath(base, path string) string {
+	switch {
+	case base == "":
+		return path
+	case strings.HasSuffix(base, "/") && strings.HasPrefix(path, "/"):
+		return base + path[1:]
+	case !strings.HasSuffix(base, "/") && !strings.HasPrefix(path, "/"):
+		return base + "/" + path
+	}
+	return base + path
+}
+
+// setCookieDomain replaces the Domain attribute of a Set-Cookie value, leaving
+// host-only cookies unchanged
+func setCookieDomain(cookie, domain string) string {
+	attrs := strings.Split(cookie, ";")
+	for i, attr := range attrs[1:] {
+		name, _, _ := strings.Cut(attr, "=")
+		if strings.EqualFold(strings.TrimSpace(name), "domain") {
+			attrs[i+1] = " Domain=" + domain
+		}
+	}
+	return strings.Join(attrs, ";")
+}
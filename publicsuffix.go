@@ -0,0 +1,207 @@
+package headers
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+//go:generate go run gen_publicsuffix.go
+
+//go:embed publicsuffix.txt
+var publicSuffixData string
+
+// ErrNoRegistrableDomain is returned for IP addresses and hosts that are
+// themselves public suffixes
+var ErrNoRegistrableDomain = errors.New("headers: host has no registrable domain")
+
+var (
+	publicSuffixOnce  sync.Once
+	publicSuffixRules map[string]bool // rule text, including any "*." or "!" prefix -> ICANN section
+)
+
+// loadPublicSuffixes parses the embedded list once
+func loadPublicSuffixes() map[string]bool {
+	publicSuffixOnce.Do(func() {
+		publicSuffixRules = make(map[string]bool, 10000)
+		for line := range strings.SplitSeq(publicSuffixData, "\n") {
+			rule, section, ok := strings.Cut(line, " ")
+			if !ok || strings.HasPrefix(line, "#") {
+				continue
+			}
+			publicSuffixRules[rule] = section == "i"
+		}
+	})
+	return publicSuffixRules
+}
+
+// PublicSuffix returns the public suffix of host using the embedded Public
+// Suffix List, and whether the matching rule is an ICANN rule rather than a
+// private one such as github.io. Hosts matching no rule fall back to their last
+// label, as the list's implicit "*" rule requires. IDN hosts may be given in
+// Unicode or punycode; the suffix is returned in the same form. IP addresses
+// have no public suffix.
+func PublicSuffix(host string) (suffix string, icann bool) {
+	labels := hostLabels(host)
+	if labels == nil {
+		return "", false
+	}
+	n, icann := publicSuffixLen(labels)
+	return strings.Join(labels[len(labels)-n:], "."), icann
+}
+
+// EffectiveTLDPlusOne returns the registrable domain of host: its public suffix
+// plus one more label, e.g. "example.co.uk" for "www.example.co.uk"
+func EffectiveTLDPlusOne(host string) (string, error) {
+	labels := hostLabels(host)
+	if labels == nil {
+		return "", fmt.Errorf("%w: %q", ErrNoRegistrableDomain, host)
+	}
+	n, _ := publicSuffixLen(labels)
+	if n >= len(labels) {
+		return "", fmt.Errorf("%w: %q", ErrNoRegistrableDomain, host)
+	}
+	return strings.Join(labels[len(labels)-n-1:], "."), nil
+}
+
+// hostLabels splits a lowercased host into labels, or returns nil for IP
+// addresses and empty hosts
+func hostLabels(host string) []string {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" || net.ParseIP(strings.Trim(host, "[]")) != nil {
+		return nil
+	}
+	return strings.Split(host, ".")
+}
+
+// publicSuffixLen returns how many trailing labels form the public suffix, and
+// whether the matching rule is in the ICANN section. Exception rules beat
+// wildcards, and otherwise the longest matching rule wins.
+func publicSuffixLen(labels []string) (int, bool) {
+	rules := loadPublicSuffixes()
+	unicode := make([]string, len(labels))
+	for i, label := range labels {
+		unicode[i] = toUnicodeLabel(label)
+	}
+
+	for i := range unicode {
+		name := strings.Join(unicode[i:], ".")
+		if icann, ok := rules["!"+name]; ok {
+			return len(unicode) - i - 1, icann
+		}
+		if icann, ok := rules[name]; ok {
+			return len(unicode) - i, icann
+		}
+		if i+1 < len(unicode) {
+			if icann, ok := rules["*."+strings.Join(unicode[i+1:], ".")]; ok {
+				return len(unicode) - i, icann
+			}
+		}
+	}
+	return 1, false
+}
+
+// toUnicodeLabel decodes a punycode ("xn--") label, returning other labels and
+// malformed punycode unchanged
+func toUnicodeLabel(label string) string {
+	encoded, ok := strings.CutPrefix(label, "xn--")
+	if !ok {
+		return label
+	}
+	decoded, err := decodePunycode(encoded)
+	if err != nil {
+		return label
+	}
+	return strings.ToLower(decoded)
+}
+
+// Punycode parameters from RFC 3492
+const (
+	punycodeBase        = 36
+	punycodeTMin        = 1
+	punycodeTMax        = 26
+	punycodeSkew        = 38
+	punycodeDamp        = 700
+	punycodeInitialBias = 72
+	punycodeInitialN    = 128
+)
+
+var errInvalidPunycode = errors.New("headers: invalid punycode")
+
+// decodePunycode decodes a punycode string without its "xn--" prefix
+func decodePunycode(s string) (string, error) {
+	var output []rune
+	if pos := strings.LastIndexByte(s, '-'); pos >= 0 {
+		for _, c := range []byte(s[:pos]) {
+			if c >= 0x80 {
+				return "", errInvalidPunycode
+			}
+			output = append(output, rune(c))
+		}
+		s = s[pos+1:]
+	}
+
+	n, bias, i := punycodeInitialN, punycodeInitialBias, 0
+	for len(s) > 0 {
+		oldI, w := i, 1
+		for k := punycodeBase; ; k += punycodeBase {
+			if len(s) == 0 {
+				return "", errInvalidPunycode
+			}
+			d := punycodeDigit(s[0])
+			s = s[1:]
+			if d < 0 || d > (1<<30-i)/w {
+				return "", errInvalidPunycode
+			}
+			i += d * w
+			t := min(max(k-bias, punycodeTMin), punycodeTMax)
+			if d < t {
+				break
+			}
+			w *= punycodeBase - t
+		}
+		bias = punycodeAdapt(i-oldI, len(output)+1, oldI == 0)
+		n += i / (len(output) + 1)
+		i %= len(output) + 1
+		if n > 0x10FFFF {
+			return "", errInvalidPunycode
+		}
+		output = append(output, 0)
+		copy(output[i+1:], output[i:])
+		output[i] = rune(n)
+		i++
+	}
+	return string(output), nil
+}
+
+// punycodeDigit returns the value of a basic code point, or -1
+func punycodeDigit(c byte) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c-'0') + 26
+	case c >= 'a' && c <= 'z':
+		return int(c - 'a')
+	case c >= 'A' && c <= 'Z':
+		return int(c - 'A')
+	}
+	return -1
+}
+
+// punycodeAdapt is the RFC 3492 bias adaptation function
+func punycodeAdapt(delta, numPoints int, first bool) int {
+	if first {
+		delta /= punycodeDamp
+	} else {
+		delta /= 2
+	}
+	delta += delta / numPoints
+	k := 0
+	for delta > ((punycodeBase-punycodeTMin)*punycodeTMax)/2 {
+		delta /= punycodeBase - punycodeTMin
+		k += punycodeBase
+	}
+	return k + (punycodeBase-punycodeTMin+1)*delta/(delta+punycodeSkew)
+}
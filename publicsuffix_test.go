@@ -0,0 +1,93 @@
+package headers
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPublicSuffix(t *testing.T) {
+	tests := []struct {
+		host   string
+		suffix string
+		icann  bool
+	}{
+		// ICANN and private rules
+		{"www.example.co.uk", "co.uk", true},
+		{"Example.COM", "com", true},
+		{"foo.github.io", "github.io", false},
+		{"github.io", "github.io", false},
+
+		// Wildcard rule *.kawasaki.jp with exception !city.kawasaki.jp
+		{"a.b.kawasaki.jp", "b.kawasaki.jp", true},
+		{"b.kawasaki.jp", "b.kawasaki.jp", true},
+		{"city.kawasaki.jp", "kawasaki.jp", true},
+
+		// Wildcard rule *.ck with exception !www.ck
+		{"a.b.foo.ck", "foo.ck", true},
+		{"www.ck", "ck", true},
+		{"a.www.ck", "ck", true},
+
+		// IDNs keep the form they were given in
+		{"www.example.公司.cn", "公司.cn", true},
+		{"www.example.xn--55qx5d.cn", "xn--55qx5d.cn", true},
+
+		// Trailing dots, single labels and unlisted TLDs
+		{"www.example.co.uk.", "co.uk", true},
+		{"localhost", "localhost", false},
+		{"com", "com", true},
+		{"foo.unknowntld", "unknowntld", false},
+
+		// IP literals have no public suffix
+		{"192.168.0.1", "", false},
+		{"::1", "", false},
+		{"[::1]", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		suffix, icann := PublicSuffix(tt.host)
+		if suffix != tt.suffix || icann != tt.icann {
+			t.Errorf("PublicSuffix(%q) = %q, %v; want %q, %v", tt.host, suffix, icann, tt.suffix, tt.icann)
+		}
+	}
+}
+
+func TestEffectiveTLDPlusOne(t *testing.T) {
+	tests := []struct {
+		host string
+		want string // "" expects ErrNoRegistrableDomain
+	}{
+		{"www.example.co.uk", "example.co.uk"},
+		{"Example.COM", "example.com"},
+		{"foo.github.io", "foo.github.io"},
+		{"a.b.foo.github.io", "foo.github.io"},
+		{"github.io", ""},
+		{"a.b.kawasaki.jp", "a.b.kawasaki.jp"},
+		{"b.kawasaki.jp", ""},
+		{"city.kawasaki.jp", "city.kawasaki.jp"},
+		{"www.city.kawasaki.jp", "city.kawasaki.jp"},
+		{"www.ck", "www.ck"},
+		{"a.www.ck", "www.ck"},
+		{"foo.ck", ""},
+		{"www.example.公司.cn", "example.公司.cn"},
+		{"www.example.xn--55qx5d.cn", "example.xn--55qx5d.cn"},
+		{"xn--55qx5d.cn", ""},
+		{"example.com.", "example.com"},
+		{"localhost", ""},
+		{"com", ""},
+		{"192.168.0.1", ""},
+		{"[::1]", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		got, err := EffectiveTLDPlusOne(tt.host)
+		if tt.want == "" {
+			if !errors.Is(err, ErrNoRegistrableDomain) {
+				t.Errorf("EffectiveTLDPlusOne(%q) = %q, %v; want ErrNoRegistrableDomain", tt.host, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("EffectiveTLDPlusOne(%q) = %q, %v; want %q", tt.host, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package headers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Range errors
+var (
+	ErrInvalidRange        = errors.New("headers: invalid range")
+	ErrRangeNotSatisfiable = errors.New("headers: range not satisfiable")
+	ErrInvalidContentRange = errors.New("headers: invalid content range")
+)
+
+// ByteRange represents a single byte range of a Range header.
+//
+// The zero-based positions follow RFC 9110 section 14.1.2:
+//   - First >= 0 and Last >= First selects "first-last"
+//   - First >= 0 and Last == -1 selects the open-ended "first-"
+//   - First == -1 and Last > 0 selects the suffix "-Last" (the final Last bytes)
+type ByteRange struct {
+	First int64
+	Last  int64
+}
+
+// BytesBetween returns the inclusive byte range first-last
+func BytesBetween(first, last int64) ByteRange {
+	return ByteRange{First: first, Last: last}
+}
+
+// BytesFrom returns the open-ended byte range starting at first
+func BytesFrom(first int64) ByteRange {
+	return ByteRange{First: first, Last: -1}
+}
+
+// BytesSuffix returns the byte range selecting the final length bytes
+func BytesSuffix(length int64) ByteRange {
+	return ByteRange{First: -1, Last: length}
+}
+
+// IsSuffix reports whether the range is a suffix range
+func (r ByteRange) IsSuffix() bool {
+	return r.First < 0
+}
+
+// IsOpenEnded reports whether the range has no last position
+func (r ByteRange) IsOpenEnded() bool {
+	return r.First >= 0 && r.Last < 0
+}
+
+// Valid reports whether the range is syntactically valid
+func (r ByteRange) Valid() bool {
+	switch {
+	case r.IsSuffix():
+		return r.First == -1 && r.Last > 0
+	case r.IsOpenEnded():
+		return r.Last == -1
+	default:
+		return r.Last >= r.First
+	}
+}
+
+// String returns the range in byte-range-spec form, e.g. "0-499", "500-" or "-200"
+func (r ByteRange) String() string {
+	switch {
+	case r.IsSuffix():
+		return "-" + strconv.FormatInt(r.Last, 10)
+	case r.IsOpenEnded():
+		return strconv.FormatInt(r.First, 10) + "-"
+	default:
+		return strconv.FormatInt(r.First, 10) + "-" + strconv.FormatInt(r.Last, 10)
+	}
+}
+
+// Resolve returns the absolute start offset and length of the range for a
+// representation of the given size, clamping the last position to the size
+func (r ByteRange) Resolve(size int64) (start, length int64, err error) {
+	if !r.Valid() || size < 0 {
+		return 0, 0, ErrInvalidRange
+	}
+
+	switch {
+	case r.IsSuffix():
+		if size == 0 {
+			return 0, 0, ErrRangeNotSatisfiable
+		}
+		length = min(r.Last, size)
+		return size - length, length, nil
+	case r.First >= size:
+		return 0, 0, ErrRangeNotSatisfiable
+	case r.IsOpenEnded():
+		return r.First, size - r.First, nil
+	default:
+		last := min(r.Last, size-1)
+		return r.First, last - r.First + 1, nil
+	}
+}
+
+// NewRange builds a Range header value in the bytes unit from one or more ranges
+func NewRange(ranges ...ByteRange) Range {
+	specs := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		specs = append(specs, r.String())
+	}
+	return RangeBytes + Range(strings.Join(specs, ","))
+}
+
+// ParseRange parses a Range header value in the bytes unit
+func ParseRange(value string) ([]ByteRange, error) {
+	value = strings.TrimSpace(value)
+	unit, set, ok := strings.Cut(value, "=")
+	if !ok || !strings.EqualFold(strings.TrimSpace(unit), "bytes") {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidRange, value)
+	}
+
+	var ranges []ByteRange
+	for spec := range strings.SplitSeq(set, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			// Empty list elements are permitted and ignored
+			continue
+		}
+
+		r, err := parseByteRangeSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, r)
+	}
+
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidRange, value)
+	}
+	return ranges, nil
+}
+
+func parseByteRangeSpec(spec string) (ByteRange, error) {
+	firstStr, lastStr, ok := strings.Cut(spec, "-")
+	if !ok {
+		return ByteRange{}, fmt.Errorf("%w: %q", ErrInvalidRange, spec)
+	}
+	firstStr = strings.TrimSpace(firstStr)
+	lastStr = strings.TrimSpace(lastStr)
+
+	var r ByteRange
+	switch {
+	case firstStr == "":
+		n, err := parseRangeInt(lastStr)
+		if err != nil || n == 0 {
+			return ByteRange{}, fmt.Errorf("%w: %q", ErrInvalidRange, spec)
+		}
+		r = BytesSuffix(n)
+	case lastStr == "":
+		n, err := parseRangeInt(firstStr)
+		if err != nil {
+			return ByteRange{}, fmt.Errorf("%w: %q", ErrInvalidRange, spec)
+		}
+		r = BytesFrom(n)
+	default:
+		first, err := parseRangeInt(firstStr)
+		if err != nil {
+			return ByteRange{}, fmt.Errorf("%w: %q", ErrInvalidRange, spec)
+		}
+		last, err := parseRangeInt(lastStr)
+		if err != nil || last < first {
+			return ByteRange{}, fmt.Errorf("%w: %q", ErrInvalidRange, spec)
+		}
+		r = BytesBetween(first, last)
+	}
+	return r, nil
+}
+
+// parseRangeInt parses a non-negative decimal made only of digits
+func parseRangeInt(s string) (int64, error) {
+	if s == "" || strings.TrimLeft(s, "0123456789") != "" {
+		return 0, ErrInvalidRange
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// ValidateRanges checks the ranges against a representation of the given size
+// and returns only the satisfiable ones. It fails with ErrRangeNotSatisfiable
+// when none of them can be served, and with ErrInvalidRange when any range is
+// malformed or the ranges overlap once resolved.
+func ValidateRanges(ranges []ByteRange, size int64) ([]ByteRange, error) {
+	if len(ranges) == 0 {
+		return nil, ErrInvalidRange
+	}
+
+	type span struct{ start, end int64 }
+	var (
+		satisfiable []ByteRange
+		spans       []span
+	)
+	for _, r := range ranges {
+		start, length, err := r.Resolve(size)
+		if errors.Is(err, ErrRangeNotSatisfiable) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", err, r.String())
+		}
+
+		end := start + length
+		for _, s := range spans {
+			if start < s.end && s.start < end {
+				return nil, fmt.Errorf("%w: %q overlaps another range", ErrInvalidRange, r.String())
+			}
+		}
+		spans = append(spans, span{start, end})
+		satisfiable = append(satisfiable, BytesBetween(start, end-1))
+	}
+
+	if len(satisfiable) == 0 {
+		return nil, ErrRangeNotSatisfiable
+	}
+	return satisfiable, nil
+}
+
+// ContentRangeSpec represents a parsed Content-Range header in the bytes unit.
+// Size is -1 when the complete length is unknown ("*"); Unsatisfied marks the
+// "bytes */size" form sent with a 416 response.
+type ContentRangeSpec struct {
+	First       int64
+	Last        int64
+	Size        int64
+	Unsatisfied bool
+}
+
+// Length returns the number of bytes covered by the range
+func (c ContentRangeSpec) Length() int64 {
+	if c.Unsatisfied {
+		return 0
+	}
+	return c.Last - c.First + 1
+}
+
+// ContentRange returns the spec formatted as a Content-Range header value
+func (c ContentRangeSpec) ContentRange() ContentRange {
+	size := "*"
+	if c.Size >= 0 {
+		size = strconv.FormatInt(c.Size, 10)
+	}
+	if c.Unsatisfied {
+		return ContentRange("bytes */" + size)
+	}
+	return ContentRange("bytes " + strconv.FormatInt(c.First, 10) + "-" + strconv.FormatInt(c.Last, 10) + "/" + size)
+}
+
+// NewContentRange builds a Content-Range header value; pass a size of -1 when
+// the complete length is unknown
+func NewContentRange(first, last, size int64) ContentRange {
+	return ContentRangeSpec{First: first, Last: last, Size: size}.ContentRange()
+}
+
+// NewUnsatisfiedContentRange builds the Content-Range value for a 416 response
+func NewUnsatisfiedContentRange(size int64) ContentRange {
+	return ContentRangeSpec{Size: size, Unsatisfied: true}.ContentRange()
+}
+
+// ParseContentRange parses a Content-Range header value in the bytes unit
+func ParseContentRange(value string) (ContentRangeSpec, error) {
+	invalid := fmt.Errorf("%w: %q", ErrInvalidContentRange, value)
+
+	unit, rest, ok := strings.Cut(strings.TrimSpace(value), " ")
+	if !ok || !strings.EqualFold(unit, "bytes") {
+		return ContentRangeSpec{}, invalid
+	}
+	rangePart, sizePart, ok := strings.Cut(strings.TrimSpace(rest), "/")
+	if !ok {
+		return ContentRangeSpec{}, invalid
+	}
+
+	spec := ContentRangeSpec{Size: -1}
+	if sizePart != "*" {
+		size, err := parseRangeInt(sizePart)
+		if err != nil {
+			return ContentRangeSpec{}, invalid
+		}
+		spec.Size = size
+	}
+
+	if rangePart == "*" {
+		// The unsatisfied form always carries the complete length
+		if spec.Size < 0 {
+			return ContentRangeSpec{}, invalid
+		}
+		spec.Unsatisfied = true
+		return spec, nil
+	}
+
+	firstStr, lastStr, ok := strings.Cut(rangePart, "-")
+	if !ok {
+		return ContentRangeSpec{}, invalid
+	}
+	first, err := parseRangeInt(firstStr)
+	if err != nil {
+		return ContentRangeSpec{}, invalid
+	}
+	last, err := parseRangeInt(lastStr)
+	if err != nil || last < first || (spec.Size >= 0 && last >= spec.Size) {
+		return ContentRangeSpec{}, invalid
+	}
+	spec.First, spec.Last = first, last
+	return spec, nil
+}
+
+// IfRangeETag returns an If-Range value that validates against an entity tag.
+// Weak tags are not allowed in If-Range, so they are rejected by returning "".
+func IfRangeETag(etag string) string {
+	etag = strings.TrimSpace(etag)
+	if etag == "" {
+		return ""
+	}
+	if !strings.HasPrefix(etag, `"`) && !strings.HasPrefix(etag, "W/") {
+		etag = `"` + etag + `"`
+	}
+
+	tag, err := ParseETag(etag)
+	if err != nil || tag.Weak {
+		return ""
+	}
+	return tag.String()
+}
+
+// IfRangeDate returns an If-Range value that validates against a Last-Modified date
+func IfRangeDate(t time.Time) string {
+	return FormatHTTPDate(t)
+}
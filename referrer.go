@@ -0,0 +1,206 @@
+package headers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// refererMaxLength is the longest Referer browsers send before falling back
+// to the origin
+const refererMaxLength = 4096
+
+// ReferrerInfo describes a request for computing its Referer and Origin headers
+type ReferrerInfo struct {
+	// Source is the URL of the document making the request; empty for
+	// browser-initiated requests, which carry no referrer
+	Source string
+	// Target is the requested URL
+	Target string
+	// Method is the request method, GET when empty
+	Method string
+	// Mode is the request mode; CORS and WebSocket requests always carry Origin
+	Mode SecFetchMode
+	// Policy is the referrer policy in effect, ReferrerPolicyDefault when empty
+	Policy ReferrerPolicy
+}
+
+// Referrer holds the computed Referer and Origin values; empty fields are not sent
+type Referrer struct {
+	Referer string
+	Origin  string
+}
+
+// ParseReferrerPolicy returns the policy a browser applies for a
+// Referrer-Policy header: the last recognized token, so that new policies can
+// be listed after fallbacks. It returns an empty policy when none is recognized.
+func ParseReferrerPolicy(values ...string) ReferrerPolicy {
+	var policy ReferrerPolicy
+	for _, value := range values {
+		for token := range strings.SplitSeq(value, ",") {
+			candidate := ReferrerPolicy(strings.ToLower(strings.TrimSpace(token)))
+			if candidate.Valid() {
+				policy = candidate
+			}
+		}
+	}
+	return policy
+}
+
+// Valid reports whether the policy is one of the eight defined policies
+func (p ReferrerPolicy) Valid() bool {
+	switch p {
+	case ReferrerPolicyNoReferrer, ReferrerPolicyNoReferrerWhenDowngrade, ReferrerPolicySameOrigin,
+		ReferrerPolicyOrigin, ReferrerPolicyStrictOrigin, ReferrerPolicyOriginWhenCrossOrigin,
+		ReferrerPolicyStrictOriginWhenCrossOrigin, ReferrerPolicyUnsafeURL:
+		return true
+	}
+	return false
+}
+
+// ResolveReferrer computes the Referer and Origin headers a browser sends,
+// following the Referrer Policy and Fetch specifications. The Referer never
+// includes the fragment or credentials of the source URL, and is reduced to the
+// origin or dropped depending on the policy, whether the request is
+// cross-origin, and whether it downgrades from HTTPS to an insecure URL.
+func ResolveReferrer(info ReferrerInfo) (Referrer, error) {
+	target, err := url.Parse(info.Target)
+	if err != nil {
+		return Referrer{}, err
+	}
+	if !target.IsAbs() {
+		return Referrer{}, fmt.Errorf("headers: target URL %q is not absolute", info.Target)
+	}
+	if info.Source == "" {
+		return Referrer{}, nil
+	}
+	source, err := url.Parse(info.Source)
+	if err != nil {
+		return Referrer{}, err
+	}
+
+	policy := info.Policy
+	if policy == "" {
+		policy = ReferrerPolicyDefault
+	} else if !policy.Valid() {
+		return Referrer{}, fmt.Errorf("headers: unknown referrer policy %q", policy)
+	}
+
+	return Referrer{
+		Referer: referrerFor(source, target, policy),
+		Origin:  originFor(source, target, info.Method, info.Mode, policy),
+	}, nil
+}
+
+// Apply copies the values into header options
+func (r Referrer) Apply(opts *HeaderOpts) {
+	opts.Referer = r.Referer
+	opts.Origin = r.Origin
+}
+
+// referrerFor applies the policy to the source URL
+func referrerFor(source, target *url.URL, policy ReferrerPolicy) string {
+	scheme := strings.ToLower(source.Scheme)
+	if scheme != "http" && scheme != "https" {
+		// Local and opaque schemes such as about:, data: and blob: send no referrer
+		return ""
+	}
+
+	full := stripReferrer(source)
+	origin := serializeOrigin(source) + "/"
+	if len(full) > refererMaxLength {
+		full = origin
+	}
+	sameOrigin := SameOrigin(source, target)
+	downgrade := isPotentiallyTrustworthy(source) && !isPotentiallyTrustworthy(target)
+
+	switch policy {
+	case ReferrerPolicyNoReferrer:
+		return ""
+	case ReferrerPolicyOrigin:
+		return origin
+	case ReferrerPolicyUnsafeURL:
+		return full
+	case ReferrerPolicyStrictOrigin:
+		if downgrade {
+			return ""
+		}
+		return origin
+	case ReferrerPolicySameOrigin:
+		if sameOrigin {
+			return full
+		}
+		return ""
+	case ReferrerPolicyOriginWhenCrossOrigin:
+		if sameOrigin {
+			return full
+		}
+		return origin
+	case ReferrerPolicyNoReferrerWhenDowngrade:
+		if downgrade {
+			return ""
+		}
+		return full
+	default: // strict-origin-when-cross-origin
+		switch {
+		case sameOrigin:
+			return full
+		case downgrade:
+			return ""
+		}
+		return origin
+	}
+}
+
+// originFor computes the Origin header: always for cross-origin CORS and
+// WebSocket requests, and for other requests unless the method is GET or HEAD.
+// Outside CORS mode it is "null" when the policy would hide the origin.
+func originFor(source, target *url.URL, method string, mode SecFetchMode, policy ReferrerPolicy) string {
+	origin := serializeOrigin(source)
+	if origin == "" {
+		origin = "null"
+	}
+	sameOrigin := SameOrigin(source, target)
+
+	if mode == SecFetchModeWebSocket || (mode == SecFetchModeCORS && !sameOrigin) {
+		return origin
+	}
+	switch strings.ToUpper(method) {
+	case "", http.MethodGet, http.MethodHead:
+		return ""
+	}
+	if mode == SecFetchModeCORS {
+		// The referrer policy only hides the origin of non-CORS requests
+		return origin
+	}
+
+	switch policy {
+	case ReferrerPolicyNoReferrer:
+		return "null"
+	case ReferrerPolicyNoReferrerWhenDowngrade, ReferrerPolicyStrictOrigin, ReferrerPolicyStrictOriginWhenCrossOrigin:
+		if strings.EqualFold(source.Scheme, "https") && !strings.EqualFold(target.Scheme, "https") {
+			return "null"
+		}
+	case ReferrerPolicySameOrigin:
+		if !sameOrigin {
+			return "null"
+		}
+	}
+	return origin
+}
+
+// stripReferrer removes credentials and the fragment from a referrer URL,
+// normalizing the host as a URL parser would
+func stripReferrer(u *url.URL) string {
+	stripped := *u
+	stripped.Scheme = strings.ToLower(u.Scheme)
+	stripped.Host = strings.TrimPrefix(serializeOrigin(u), stripped.Scheme+"://")
+	stripped.User = nil
+	stripped.Fragment = ""
+	stripped.RawFragment = ""
+	if stripped.Path == "" && stripped.RawPath == "" && stripped.Opaque == "" {
+		stripped.Path = "/"
+	}
+	return stripped.String()
+}
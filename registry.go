@@ -0,0 +1,121 @@
+package headers
+
+import (
+	_ "embed"
+	"path"
+	"strings"
+	"sync"
+)
+
+//go:generate go run gen_mediatypes.go
+
+//go:embed mediatypes.txt
+var mediaTypesData string
+
+// MediaTypeInfo describes a registered media type
+type MediaTypeInfo struct {
+	ContentType  ContentType
+	Source       string // iana, apache, nginx, override or none
+	Extensions   []string
+	Compressible bool
+	Known        bool // Whether the registry states compressibility explicitly
+}
+
+// mediaRegistry holds the parsed registry indexes
+type mediaRegistry struct {
+	byType      map[ContentType]*MediaTypeInfo
+	byExtension map[string]*MediaTypeInfo
+}
+
+var (
+	registryOnce sync.Once
+	registry     mediaRegistry
+)
+
+// loadRegistry parses the embedded registry once. Lines are ordered by source
+// preference, so the first type listing an extension owns it.
+func loadRegistry() *mediaRegistry {
+	registryOnce.Do(func() {
+		registry = mediaRegistry{
+			byType:      make(map[ContentType]*MediaTypeInfo),
+			byExtension: make(map[string]*MediaTypeInfo),
+		}
+		for line := range strings.SplitSeq(mediaTypesData, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) != 4 || strings.HasPrefix(line, "#") {
+				continue
+			}
+
+			info := &MediaTypeInfo{
+				ContentType:  ContentType(fields[0]),
+				Source:       fields[1],
+				Compressible: fields[2] == "1",
+				Known:        fields[2] != "?",
+			}
+			if fields[3] != "-" {
+				info.Extensions = strings.Split(fields[3], ",")
+			}
+			if _, seen := registry.byType[info.ContentType]; !seen {
+				registry.byType[info.ContentType] = info
+			}
+			for _, ext := range info.Extensions {
+				if _, seen := registry.byExtension[ext]; !seen {
+					registry.byExtension[ext] = info
+				}
+			}
+		}
+	})
+	return &registry
+}
+
+// LookupMediaType returns the registry entry for a content type, ignoring parameters
+func LookupMediaType(ct ContentType) (MediaTypeInfo, bool) {
+	info, ok := loadRegistry().byType[ct.Essence()]
+	if !ok {
+		return MediaTypeInfo{}, false
+	}
+	return *info, true
+}
+
+// LookupExtension returns the content type registered for a file extension,
+// given with or without the leading dot
+func LookupExtension(ext string) (ContentType, bool) {
+	ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+	info, ok := loadRegistry().byExtension[ext]
+	if !ok {
+		return "", false
+	}
+	return info.ContentType, true
+}
+
+// ExtensionsFor returns the file extensions registered for a content type,
+// preferred extension first
+func ExtensionsFor(ct ContentType) []string {
+	info, ok := LookupMediaType(ct)
+	if !ok {
+		return nil
+	}
+	return append([]string(nil), info.Extensions...)
+}
+
+// TypeByFilename returns the content type for a filename's extension, or
+// application/octet-stream when the extension is unknown
+func TypeByFilename(filename string) ContentType {
+	if ct, ok := LookupExtension(path.Ext(filename)); ok {
+		return ct
+	}
+	return ContentTypeApplicationOctet
+}
+
+// IsCompressible reports whether responses of the content type benefit from
+// compression. Types without a registry flag fall back to textual heuristics.
+func IsCompressible(ct ContentType) bool {
+	if info, ok := LookupMediaType(ct); ok && info.Known {
+		return info.Compressible
+	}
+	m, err := ct.Parse()
+	if err != nil {
+		return false
+	}
+	return m.IsText() || m.Essence() == "image/svg+xml"
+}
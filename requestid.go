@@ -0,0 +1,198 @@
+package headers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// RequestIDMaxLengthDefault is the longest incoming request ID accepted by default
+const RequestIDMaxLengthDefault = 128
+
+// crockfordAlphabet is the Crockford base32 alphabet used by ULIDs
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// RequestIDGenerator creates a new request ID
+type RequestIDGenerator func() string
+
+// requestIDKey is the context key under which the request ID is stored
+type requestIDKey struct{}
+
+// NewUUIDv4 returns a random UUID (RFC 9562 version 4)
+func NewUUIDv4() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0F | 0x40
+	b[8] = b[8]&0x3F | 0x80
+	return formatUUID(b)
+}
+
+// NewUUIDv7 returns a time-ordered UUID (RFC 9562 version 7) whose first 48
+// bits are the Unix time in milliseconds
+func NewUUIDv7() string {
+	var b [16]byte
+	rand.Read(b[6:])
+	ms := uint64(time.Now().UnixMilli())
+	b[0], b[1], b[2] = byte(ms>>40), byte(ms>>32), byte(ms>>24)
+	b[3], b[4], b[5] = byte(ms>>16), byte(ms>>8), byte(ms)
+	b[6] = b[6]&0x0F | 0x70
+	b[8] = b[8]&0x3F | 0x80
+	return formatUUID(b)
+}
+
+// formatUUID renders 16 bytes in the 8-4-4-4-12 hex form
+func formatUUID(b [16]byte) string {
+	var s [36]byte
+	hex.Encode(s[0:8], b[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], b[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], b[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], b[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], b[10:])
+	return string(s[:])
+}
+
+// NewULID returns a ULID: a 48-bit millisecond timestamp and 80 random bits in
+// 26 Crockford base32 characters, which sort by creation time
+func NewULID() string {
+	var b [16]byte
+	rand.Read(b[6:])
+	ms := uint64(time.Now().UnixMilli())
+	b[0], b[1], b[2] = byte(ms>>40), byte(ms>>32), byte(ms>>24)
+	b[3], b[4], b[5] = byte(ms>>16), byte(ms>>8), byte(ms)
+
+	// Encode the 128 bits from the least significant end, 5 bits per character
+	hi, lo := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+	var s [26]byte
+	for i := len(s) - 1; i >= 0; i-- {
+		s[i] = crockfordAlphabet[lo&0x1F]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(s[:])
+}
+
+// WithRequestID returns a context carrying the request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, if any
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// ValidRequestID reports whether an incoming request ID is safe to accept: at
+// most maxLength characters from letters, digits and "-_.:"
+func ValidRequestID(id string, maxLength int) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// RequestIDProvider returns a header provider yielding the request ID stored in
+// the build context, or a new one from generate (NewUUIDv4 when nil)
+func RequestIDProvider(generate RequestIDGenerator) HeaderProvider {
+	if generate == nil {
+		generate = NewUUIDv4
+	}
+	return func(ctx context.Context) (string, error) {
+		if id, ok := RequestIDFromContext(ctx); ok {
+			return id, nil
+		}
+		return generate(), nil
+	}
+}
+
+// RequestIDTransport is an http.RoundTripper that stamps each outgoing request
+// with a request ID, propagating the one in the request context when present
+type RequestIDTransport struct {
+	Transport http.RoundTripper // Underlying transport, http.DefaultTransport when nil
+	Header    string            // Header name, HeaderRequestID when empty
+	Generate  RequestIDGenerator
+}
+
+// NewRequestIDTransport creates a RequestIDTransport over next generating UUIDv4 IDs
+func NewRequestIDTransport(next http.RoundTripper) *RequestIDTransport {
+	return &RequestIDTransport{Transport: next, Generate: NewUUIDv4}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *RequestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	header := t.Header
+	if header == "" {
+		header = HeaderRequestID
+	}
+
+	if req.Header.Get(header) == "" {
+		id, _ := RequestIDProvider(t.Generate)(req.Context())
+		req = req.Clone(req.Context())
+		req.Header.Set(header, id)
+	}
+	return next.RoundTrip(req)
+}
+
+// RequestIDs is HTTP middleware that gives every request an ID: a valid one
+// sent by the client is kept, otherwise a new one is generated. The ID is stored
+// in the request context for RequestIDFromContext and echoed on the response.
+type RequestIDs struct {
+	Header    string             // Header name, HeaderRequestID when empty
+	Generate  RequestIDGenerator // NewUUIDv4 when nil
+	MaxLength int                // RequestIDMaxLengthDefault when zero
+	// IgnoreIncoming always generates a new ID, for untrusted clients
+	IgnoreIncoming bool
+}
+
+// RequestIDHandler wraps next with RequestIDs using default settings
+func RequestIDHandler(next http.Handler) http.Handler {
+	return (&RequestIDs{}).Handler(next)
+}
+
+// Handler returns next wrapped with request ID handling
+func (m *RequestIDs) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := m.Header
+		if header == "" {
+			header = HeaderRequestID
+		}
+		maxLength := m.MaxLength
+		if maxLength <= 0 {
+			maxLength = RequestIDMaxLengthDefault
+		}
+		generate := m.Generate
+		if generate == nil {
+			generate = NewUUIDv4
+		}
+
+		id := r.Header.Get(header)
+		if m.IgnoreIncoming || !ValidRequestID(id, maxLength) {
+			id = generate()
+			r = r.Clone(r.Context())
+			r.Header.Set(header, id)
+		}
+
+		w.Header().Set(header, id)
+		next.ServeHTTP(w, r.WithContext(WithRequestID(r.Context(), id)))
+	})
+}
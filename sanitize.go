@@ -0,0 +1,150 @@
+package headers
+
+import (
+	"net/http"
+	"strings"
+)
+
+// RedactedValue replaces header values hidden by Redact
+const RedactedValue = "[REDACTED]"
+
+// ValueTransform rewrites one header value; returning "" drops the value
+type ValueTransform func(name, value string) string
+
+// Policy filters a header set, such as client headers forwarded upstream or
+// Builder output written to logs. Patterns are case-insensitive header names,
+// optionally ending in "*" to match a prefix such as "Sec-CH-*".
+type Policy struct {
+	Allow []string // When not empty, only matching headers pass
+	Deny  []string // Matching headers never pass, even when allowed
+	// Transforms rewrite the values of matching headers that pass. When several
+	// patterns match, an exact name wins over the longest prefix.
+	Transforms map[string]ValueTransform
+}
+
+// NewLogPolicy creates a Policy that redacts credentials and cookies, for
+// logging headers
+func NewLogPolicy() *Policy {
+	return &Policy{Transforms: map[string]ValueTransform{
+		"Authorization":       Redact,
+		"Proxy-Authorization": Redact,
+		"Cookie":              Redact,
+		"Set-Cookie":          Redact,
+		HeaderAPIKey:          Redact,
+		HeaderSignature:       Redact,
+	}}
+}
+
+// Redact is a ValueTransform replacing the value with RedactedValue
+func Redact(name, value string) string {
+	return RedactedValue
+}
+
+// Truncate returns a ValueTransform cutting values to at most n bytes
+func Truncate(n int) ValueTransform {
+	return func(name, value string) string {
+		if len(value) > n {
+			return value[:n]
+		}
+		return value
+	}
+}
+
+// Allows reports whether a header passes the allowlist and denylist
+func (p *Policy) Allows(name string) bool {
+	if len(p.Allow) > 0 && !matchAnyPattern(p.Allow, name) {
+		return false
+	}
+	return !matchAnyPattern(p.Deny, name)
+}
+
+// Value returns the value after the header's transform, and false when the
+// header or value is dropped
+func (p *Policy) Value(name, value string) (string, bool) {
+	if !p.Allows(name) {
+		return "", false
+	}
+	if transform := p.transform(name); transform != nil {
+		value = transform(name, value)
+	}
+	return value, value != ""
+}
+
+// Filter returns the headers that pass the policy, keeping their order
+func (p *Policy) Filter(h *Headers) *Headers {
+	filtered := &Headers{}
+	for name, value := range h.All() {
+		if value, ok := p.Value(name, value); ok {
+			filtered.Add(name, value)
+		}
+	}
+	return filtered
+}
+
+// FilterHTTP returns a copy of an http.Header with the headers that pass the policy
+func (p *Policy) FilterHTTP(header http.Header) http.Header {
+	filtered := make(http.Header, len(header))
+	for name, values := range header {
+		for _, value := range values {
+			if value, ok := p.Value(name, value); ok {
+				filtered[name] = append(filtered[name], value)
+			}
+		}
+	}
+	return filtered
+}
+
+// FilterMap returns a copy of a header map, such as Builder.Build output, with
+// the headers that pass the policy
+func (p *Policy) FilterMap(headers map[string]string) map[string]string {
+	filtered := make(map[string]string, len(headers))
+	for name, value := range headers {
+		if value, ok := p.Value(name, value); ok {
+			filtered[name] = value
+		}
+	}
+	return filtered
+}
+
+// transform returns the transform of the most specific matching pattern. Ties
+// between patterns of equal specificity, such as the same prefix in different
+// case, go to the lexically smallest pattern so the result is deterministic.
+func (p *Policy) transform(name string) ValueTransform {
+	var (
+		best        ValueTransform
+		bestPattern string
+	)
+	bestLen := -2 // Below the -1 scored by the catch-all "*"
+	for pattern, transform := range p.Transforms {
+		if !matchPattern(pattern, name) {
+			continue
+		}
+		// An exact pattern outranks any prefix of the same name
+		length := 2 * len(pattern)
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			length = 2*len(prefix) - 1
+		}
+		if length > bestLen || (length == bestLen && pattern < bestPattern) {
+			best, bestPattern, bestLen = transform, pattern, length
+		}
+	}
+	return best
+}
+
+// matchAnyPattern reports whether a header name matches one of the patterns
+func matchAnyPattern(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matchPattern(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPattern matches a header name against a name or a "Prefix-*" pattern
+func matchPattern(pattern, name string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return len(name) >= len(prefix) && strings.EqualFold(name[:len(prefix)], prefix)
+	}
+	return strings.EqualFold(pattern, name)
+}
@@ -0,0 +1,64 @@
+package headers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPolicyTransform(t *testing.T) {
+	upper := func(name, value string) string { return strings.ToUpper(value) }
+	lower := func(name, value string) string { return strings.ToLower(value) }
+
+	tests := []struct {
+		name   string
+		policy Policy
+		header string
+		want   string
+	}{
+		{
+			name:   "catch-all",
+			policy: Policy{Transforms: map[string]ValueTransform{"*": Redact}},
+			header: "Authorization",
+			want:   RedactedValue,
+		},
+		{
+			name:   "prefix over catch-all",
+			policy: Policy{Transforms: map[string]ValueTransform{"*": Redact, "X-*": upper}},
+			header: "X-Trace",
+			want:   "VALUE",
+		},
+		{
+			name:   "exact over prefix",
+			policy: Policy{Transforms: map[string]ValueTransform{"Sec-CH-UA*": Redact, "sec-ch-ua": upper}},
+			header: "Sec-CH-UA",
+			want:   "VALUE",
+		},
+		{
+			name:   "longest prefix",
+			policy: Policy{Transforms: map[string]ValueTransform{"Sec-*": Redact, "Sec-CH-*": upper}},
+			header: "Sec-CH-UA-Mobile",
+			want:   "VALUE",
+		},
+		{
+			name:   "prefixes differing in case",
+			policy: Policy{Transforms: map[string]ValueTransform{"sec-ch-*": upper, "Sec-CH-*": lower}},
+			header: "Sec-CH-UA",
+			want:   "value", // "Sec-CH-*" sorts before "sec-ch-*"
+		},
+		{
+			name:   "no match",
+			policy: Policy{Transforms: map[string]ValueTransform{"X-*": Redact}},
+			header: "Accept",
+			want:   "Value",
+		},
+	}
+	for _, tt := range tests {
+		// Map iteration order varies, so repeat to catch nondeterminism
+		for range 20 {
+			if got, _ := tt.policy.Value(tt.header, "Value"); got != tt.want {
+				t.Errorf("%s: Value(%q) = %q; want %q", tt.name, tt.header, got, tt.want)
+				break
+			}
+		}
+	}
+}
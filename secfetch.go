@@ -0,0 +1,168 @@
+package headers
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// FetchInfo describes a request as the browser sees it, for computing the
+// Sec-Fetch-* metadata headers
+type FetchInfo struct {
+	// Initiator is the URL of the document or worker making the request; empty
+	// for browser-initiated navigations such as typing in the address bar
+	Initiator string
+	// Target is the requested URL
+	Target string
+	// Redirects lists the URLs the request was redirected through before
+	// reaching Target, in order
+	Redirects []string
+	Kind      RequestKind
+	// Mode overrides the mode implied by Kind, e.g. a fetch() with mode "no-cors"
+	Mode SecFetchMode
+	// UserActivated marks navigations triggered by a user gesture
+	UserActivated bool
+}
+
+// SecFetch holds the computed Sec-Fetch-* values; empty fields are not sent
+type SecFetch struct {
+	Dest SecFetchDest
+	Mode SecFetchMode
+	Site SecFetchSite
+	User SecFetchUser
+}
+
+// ResolveSecFetch computes the Sec-Fetch-Dest, Sec-Fetch-Mode, Sec-Fetch-Site
+// and Sec-Fetch-User values as defined by the Fetch Metadata specification.
+// Browsers only send these headers to potentially trustworthy URLs, so the
+// result is empty for plain HTTP targets other than localhost.
+func ResolveSecFetch(info FetchInfo) (SecFetch, error) {
+	target, err := url.Parse(info.Target)
+	if err != nil {
+		return SecFetch{}, err
+	}
+	if !target.IsAbs() {
+		return SecFetch{}, fmt.Errorf("headers: target URL %q is not absolute", info.Target)
+	}
+	if !isPotentiallyTrustworthy(target) {
+		return SecFetch{}, nil
+	}
+
+	dest, mode, ok := kindDestMode(info.Kind)
+	if !ok {
+		return SecFetch{}, fmt.Errorf("headers: unknown request kind %q", info.Kind)
+	}
+	if info.Mode != "" {
+		mode = info.Mode
+	}
+
+	site := SecFetchSiteNone
+	if info.Initiator != "" {
+		initiator, err := url.Parse(info.Initiator)
+		if err != nil {
+			return SecFetch{}, err
+		}
+		site = SecFetchSiteSameOrigin
+		for _, hop := range info.Redirects {
+			u, err := url.Parse(hop)
+			if err != nil {
+				return SecFetch{}, err
+			}
+			site = leastSameSite(site, fetchSite(initiator, u))
+		}
+		site = leastSameSite(site, fetchSite(initiator, target))
+	}
+
+	result := SecFetch{Dest: dest, Mode: mode, Site: site}
+	if mode == SecFetchModeNavigate && info.UserActivated {
+		result.User = SecFetchUserTrue
+	}
+	return result, nil
+}
+
+// Apply copies the values into header options
+func (s SecFetch) Apply(opts *HeaderOpts) {
+	opts.SecFetchDest = s.Dest
+	opts.SecFetchMode = s.Mode
+	opts.SecFetchSite = s.Site
+	opts.SecFetchUser = s.User
+}
+
+// kindDestMode maps a request kind to its Fetch destination and default mode
+func kindDestMode(kind RequestKind) (SecFetchDest, SecFetchMode, bool) {
+	switch kind {
+	case RequestNavigation:
+		return SecFetchDestDocument, SecFetchModeNavigate, true
+	case RequestIFrame:
+		return SecFetchDestIFrame, SecFetchModeNavigate, true
+	case RequestFetch, RequestXHR:
+		return SecFetchDestEmpty, SecFetchModeCORS, true
+	case RequestImage:
+		return SecFetchDestImage, SecFetchModeNoCORS, true
+	case RequestScript:
+		return SecFetchDestScript, SecFetchModeNoCORS, true
+	case RequestModuleScript:
+		return SecFetchDestScript, SecFetchModeCORS, true
+	case RequestStyle:
+		return SecFetchDestStyle, SecFetchModeNoCORS, true
+	case RequestFont:
+		return SecFetchDestFont, SecFetchModeCORS, true
+	case RequestAudio:
+		return SecFetchDestAudio, SecFetchModeNoCORS, true
+	case RequestVideo:
+		return SecFetchDestVideo, SecFetchModeNoCORS, true
+	case RequestManifest:
+		return SecFetchDestManifest, SecFetchModeCORS, true
+	case RequestWorker:
+		return SecFetchDestWorker, SecFetchModeSameOrigin, true
+	case RequestSharedWorker:
+		return SecFetchDestSharedWorker, SecFetchModeSameOrigin, true
+	case RequestServiceWorker:
+		return SecFetchDestServiceWorker, SecFetchModeSameOrigin, true
+	case RequestObject:
+		return SecFetchDestObject, SecFetchModeNoCORS, true
+	case RequestWebSocket:
+		return SecFetchDestWebSocket, SecFetchModeWebSocket, true
+	}
+	return "", "", false
+}
+
+// fetchSite compares the initiator with one URL of the request's chain
+func fetchSite(initiator, u *url.URL) SecFetchSite {
+	switch {
+	case SameOrigin(initiator, u):
+		return SecFetchSiteSameOrigin
+	case SameSite(initiator, u):
+		return SecFetchSiteSameSite
+	}
+	return SecFetchSiteCrossSite
+}
+
+// leastSameSite returns the weaker of two Sec-Fetch-Site relations
+func leastSameSite(a, b SecFetchSite) SecFetchSite {
+	rank := map[SecFetchSite]int{
+		SecFetchSiteSameOrigin: 0,
+		SecFetchSiteSameSite:   1,
+		SecFetchSiteCrossSite:  2,
+	}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+// isPotentiallyTrustworthy reports whether a URL is a secure context target
+// (HTTPS, WSS, file, or loopback)
+func isPotentiallyTrustworthy(u *url.URL) bool {
+	switch strings.ToLower(u.Scheme) {
+	case "https", "wss", "file":
+		return true
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
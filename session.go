@@ -0,0 +1,324 @@
+package headers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// sessionMaxRedirects matches the redirect limit of browsers and net/http
+const sessionMaxRedirects = 20
+
+// redirectCredentialHeaders are removed from requests redirected to another origin
+var redirectCredentialHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", HeaderAPIKey, HeaderSignature, "X-CSRF-Token"}
+
+// Session emulates a browser tab over a Builder. It remembers the current page
+// and its referrer policy, so each request carries the Referer, Origin,
+// Sec-Fetch-* and Upgrade-Insecure-Requests headers a browser would send from
+// that page. Redirect hops recompute only Referer, Origin and Sec-Fetch-*.
+// Cookies are kept by the client's jar.
+type Session struct {
+	Client  *http.Client
+	Builder *Builder
+	Opts    HeaderOpts // Options applied to every request, e.g. User-Agent
+	// CredentialHeaders are dropped on cross-origin redirects in addition to
+	// Authorization, Proxy-Authorization, Cookie and the API key headers
+	CredentialHeaders []string
+
+	mu            sync.Mutex
+	page          *url.URL       // Current document
+	pageInitiator *url.URL       // Document that navigated to page, for reloads
+	policy        ReferrerPolicy // Referrer policy of the current document
+}
+
+// sessionRequest describes one request made by the emulated tab
+type sessionRequest struct {
+	kind          RequestKind
+	method        string
+	initiator     *url.URL
+	policy        ReferrerPolicy
+	contentType   ContentType
+	userActivated bool
+	reload        bool
+}
+
+// NewSession creates a Session. A nil client is replaced by one with a cookie
+// jar from NewCookieJar; a nil builder by an empty Builder.
+func NewSession(client *http.Client, builder *Builder) *Session {
+	if client == nil {
+		client = &http.Client{Jar: NewCookieJar()}
+	}
+	if builder == nil {
+		builder = NewBuilder(nil)
+	}
+	return &Session{Client: client, Builder: builder}
+}
+
+// NewCookieJar creates an in-memory cookie jar that scopes cookies using the
+// embedded Public Suffix List
+func NewCookieJar() http.CookieJar {
+	jar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicSuffixList{}})
+	return jar
+}
+
+// publicSuffixList adapts the embedded list to cookiejar.PublicSuffixList
+type publicSuffixList struct{}
+
+func (publicSuffixList) PublicSuffix(domain string) string {
+	suffix, _ := PublicSuffix(domain)
+	return suffix
+}
+
+func (publicSuffixList) String() string {
+	return "headers embedded public suffix list"
+}
+
+// Page returns the URL of the current document, or "" before the first navigation
+func (s *Session) Page() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.page == nil {
+		return ""
+	}
+	return s.page.String()
+}
+
+// Navigate follows a link from the current page, or opens target from the
+// address bar when there is no page yet, and makes the response the current page
+func (s *Session) Navigate(ctx context.Context, target string) (*http.Response, error) {
+	initiator, policy := s.current()
+	return s.do(ctx, target, nil, sessionRequest{
+		kind:          RequestNavigation,
+		method:        http.MethodGet,
+		initiator:     initiator,
+		policy:        policy,
+		userActivated: true,
+	})
+}
+
+// Submit posts a URL-encoded form from the current page as a navigation
+func (s *Session) Submit(ctx context.Context, target string, form url.Values) (*http.Response, error) {
+	initiator, policy := s.current()
+	return s.do(ctx, target, strings.NewReader(form.Encode()), sessionRequest{
+		kind:          RequestNavigation,
+		method:        http.MethodPost,
+		initiator:     initiator,
+		policy:        policy,
+		contentType:   ContentTypeForm,
+		userActivated: true,
+	})
+}
+
+// Reload reloads the current page, revalidating it with Cache-Control: max-age=0
+func (s *Session) Reload(ctx context.Context) (*http.Response, error) {
+	s.mu.Lock()
+	page, initiator, policy := s.page, s.pageInitiator, s.policy
+	s.mu.Unlock()
+
+	if page == nil {
+		return nil, errors.New("headers: session has no page to reload")
+	}
+	return s.do(ctx, page.String(), nil, sessionRequest{
+		kind:          RequestNavigation,
+		method:        http.MethodGet,
+		initiator:     initiator,
+		policy:        policy,
+		userActivated: true,
+		reload:        true,
+	})
+}
+
+// Fetch makes a fetch() call from the current page. A body is sent as JSON
+// unless the session options set a Content-Type.
+func (s *Session) Fetch(ctx context.Context, method, target string, body io.Reader) (*http.Response, error) {
+	initiator, policy := s.current()
+	req := sessionRequest{
+		kind:      RequestFetch,
+		method:    method,
+		initiator: initiator,
+		policy:    policy,
+	}
+	if body != nil {
+		req.contentType = ContentTypeJSON
+	}
+	return s.do(ctx, target, body, req)
+}
+
+// Load fetches a subresource of the current page, such as an image or script
+func (s *Session) Load(ctx context.Context, kind RequestKind, target string) (*http.Response, error) {
+	initiator, policy := s.current()
+	return s.do(ctx, target, nil, sessionRequest{
+		kind:      kind,
+		method:    http.MethodGet,
+		initiator: initiator,
+		policy:    policy,
+	})
+}
+
+// LoadImage fetches an image embedded in the current page
+func (s *Session) LoadImage(ctx context.Context, target string) (*http.Response, error) {
+	return s.Load(ctx, RequestImage, target)
+}
+
+// current returns the current page and its referrer policy
+func (s *Session) current() (*url.URL, ReferrerPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.page, s.policy
+}
+
+// do sends a request, recomputing the browser headers for every redirect hop,
+// and updates the current page after a navigation
+func (s *Session) do(ctx context.Context, target string, body io.Reader, sr sessionRequest) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, sr.method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.prepare(req, sr); err != nil {
+		return nil, err
+	}
+
+	client := *s.Client
+	checkRedirect := s.Client.CheckRedirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if checkRedirect != nil {
+			if err := checkRedirect(req, via); err != nil {
+				return err
+			}
+		} else if len(via) >= sessionMaxRedirects {
+			return errors.New("headers: stopped after too many redirects")
+		}
+		return s.prepareRedirect(req, sr, via)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if sr.kind == RequestNavigation && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusResetContent {
+		policy := ParseReferrerPolicy(resp.Header.Values("Referrer-Policy")...)
+		s.mu.Lock()
+		s.page = resp.Request.URL
+		s.pageInitiator = sr.initiator
+		s.policy = policy
+		s.mu.Unlock()
+	}
+	return resp, nil
+}
+
+// prepare sets the headers of the first request: the Builder output for the
+// session options together with the headers a browser derives
+func (s *Session) prepare(req *http.Request, sr sessionRequest) error {
+	opts := s.Opts
+	dest, err := deriveHeaders(&opts, req, sr, nil)
+	if err != nil {
+		return err
+	}
+
+	if opts.Accept == "" && !s.Builder.setsHeader("Accept") {
+		// Sec-Fetch-Dest is not sent to insecure targets, so fill Accept here
+		accept, encoding := DestinationAccept(opts.Profile, dest)
+		opts.Accept = accept
+		if opts.AcceptEncoding == "" && !s.Builder.setsHeader("Accept-Encoding") {
+			opts.AcceptEncoding = encoding
+		}
+	}
+	if sr.kind == RequestNavigation || sr.kind == RequestIFrame {
+		opts.UpgradeInsecureRequests = true
+	}
+	if sr.reload {
+		opts.CacheControl = CacheControlMaxAge(0)
+	}
+	if sr.contentType != "" && opts.ContentType == "" {
+		opts.ContentType = sr.contentType
+	}
+
+	headers, err := s.Builder.BuildContext(req.Context(), opts)
+	if err != nil {
+		return err
+	}
+	applyHeaders(req, headers)
+	return nil
+}
+
+// prepareRedirect updates the headers net/http copied from the previous hop.
+// Only the headers a browser derives are recomputed; the Builder output is not
+// applied again, and credentials are dropped when the hop changes origin, as
+// in the Fetch "HTTP-redirect fetch". The jar adds the cookies for the new URL.
+func (s *Session) prepareRedirect(req *http.Request, sr sessionRequest, via []*http.Request) error {
+	hops := make([]string, len(via))
+	for i, prev := range via {
+		hops[i] = prev.URL.String()
+	}
+	var derived HeaderOpts
+	if _, err := deriveHeaders(&derived, req, sr, hops); err != nil {
+		return err
+	}
+
+	if !SameOrigin(via[len(via)-1].URL, req.URL) {
+		for _, name := range redirectCredentialHeaders {
+			req.Header.Del(name)
+		}
+		for _, name := range s.CredentialHeaders {
+			req.Header.Del(name)
+		}
+	}
+	if req.Body == nil || req.Body == http.NoBody {
+		req.Header.Del("Content-Type")
+	}
+	for name, value := range map[string]string{
+		"Referer":        derived.Referer,
+		"Origin":         derived.Origin,
+		"Sec-Fetch-Dest": string(derived.SecFetchDest),
+		"Sec-Fetch-Mode": string(derived.SecFetchMode),
+		"Sec-Fetch-Site": string(derived.SecFetchSite),
+		"Sec-Fetch-User": string(derived.SecFetchUser),
+	} {
+		if value == "" {
+			req.Header.Del(name)
+		} else {
+			req.Header.Set(name, value)
+		}
+	}
+	return nil
+}
+
+// deriveHeaders fills the Sec-Fetch-* and referrer options for one hop, given
+// the URLs it was redirected through, and returns the request's destination
+func deriveHeaders(opts *HeaderOpts, req *http.Request, sr sessionRequest, redirects []string) (SecFetchDest, error) {
+	info := FetchInfo{
+		Target:        req.URL.String(),
+		Redirects:     redirects,
+		Kind:          sr.kind,
+		UserActivated: sr.userActivated,
+	}
+	if sr.initiator != nil {
+		info.Initiator = sr.initiator.String()
+	}
+	secFetch, err := ResolveSecFetch(info)
+	if err != nil {
+		return "", err
+	}
+	secFetch.Apply(opts)
+
+	dest, mode, _ := kindDestMode(sr.kind)
+	referrer, err := ResolveReferrer(ReferrerInfo{
+		Source: info.Initiator,
+		Target: info.Target,
+		Method: req.Method,
+		Mode:   mode,
+		Policy: sr.policy,
+	})
+	if err != nil {
+		return "", err
+	}
+	referrer.Apply(opts)
+	return dest, nil
+}
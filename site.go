@@ -0,0 +1,112 @@
+package headers
+
+import (
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+// SameOrigin reports whether two URLs have the same origin: scheme, host and
+// port, with default ports made explicit. WebSocket schemes compare as their
+// HTTP equivalents, and IDN hosts compare equal in Unicode and punycode form.
+// Opaque origins, such as those of data: or relative URLs, are never the same.
+func SameOrigin(a, b *url.URL) bool {
+	if !hasTupleOrigin(a) || !hasTupleOrigin(b) {
+		return false
+	}
+	return httpScheme(a.Scheme) == httpScheme(b.Scheme) &&
+		siteHost(a.Hostname()) == siteHost(b.Hostname()) &&
+		originPort(a) == originPort(b)
+}
+
+// SameSite reports whether two URLs are schemefully same-site: they share a
+// scheme and a registrable domain according to the Public Suffix List. Hosts
+// without a registrable domain, such as IP addresses, are only same-site with
+// themselves, and URLs with opaque origins are never same-site.
+func SameSite(a, b *url.URL) bool {
+	if !hasTupleOrigin(a) || !hasTupleOrigin(b) || httpScheme(a.Scheme) != httpScheme(b.Scheme) {
+		return false
+	}
+	return siteOf(a.Hostname()) == siteOf(b.Hostname())
+}
+
+// hasTupleOrigin reports whether a URL has a scheme/host/port origin rather
+// than an opaque one
+func hasTupleOrigin(u *url.URL) bool {
+	return serializeOrigin(u) != "" && u.Hostname() != ""
+}
+
+// siteOf returns the registrable domain of host in Unicode form, or the host
+// itself when it has none
+func siteOf(host string) string {
+	labels := hostLabels(host)
+	if labels == nil {
+		return siteHost(host)
+	}
+	n, _ := publicSuffixLen(labels)
+	if n < len(labels) {
+		labels = labels[len(labels)-n-1:]
+	}
+	return siteHost(strings.Join(labels, "."))
+}
+
+// siteHost normalizes a host for comparison: lowercase, no trailing dot,
+// punycode labels decoded and IP addresses in canonical form
+func siteHost(host string) string {
+	labels := hostLabels(host)
+	if labels == nil {
+		// IP addresses compare in canonical form, so ::1 matches 0:0::1
+		if addr, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+			return addr.Unmap().String()
+		}
+		return strings.ToLower(host)
+	}
+	for i, label := range labels {
+		labels[i] = toUnicodeLabel(label)
+	}
+	return strings.Join(labels, ".")
+}
+
+// httpScheme maps WebSocket schemes to the HTTP schemes they are fetched with
+func httpScheme(scheme string) string {
+	switch scheme = strings.ToLower(scheme); scheme {
+	case "ws":
+		return "http"
+	case "wss":
+		return "https"
+	}
+	return scheme
+}
+
+// originPort returns the explicit or default port of a URL
+func originPort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	switch httpScheme(u.Scheme) {
+	case "http":
+		return "80"
+	case "https":
+		return "443"
+	}
+	return ""
+}
+
+// serializeOrigin returns the ASCII serialization of a URL's origin, such as
+// "https://example.com:8443", or "" for schemes with opaque origins
+func serializeOrigin(u *url.URL) string {
+	scheme := strings.ToLower(u.Scheme)
+	switch scheme {
+	case "http", "https", "ws", "wss":
+	default:
+		return ""
+	}
+	host := strings.ToLower(u.Hostname())
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port := u.Port(); port != "" && port != originPort(&url.URL{Scheme: scheme}) {
+		host += ":" + port
+	}
+	return scheme + "://" + host
+}
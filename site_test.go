@@ -0,0 +1,115 @@
+package headers
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestSameSite(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"https://a.example.com", "https://b.example.com", true},
+		{"https://example.com:8443", "https://www.example.com", true},
+		{"wss://example.com", "https://www.example.com", true},
+		{"http://example.com", "https://example.com", false},
+
+		// Private suffixes separate sites
+		{"https://a.github.io", "https://b.github.io", false},
+		{"https://a.github.io", "https://x.a.github.io", true},
+
+		// Wildcard and exception rules
+		{"https://a.b.kawasaki.jp", "https://c.b.kawasaki.jp", false},
+		{"https://city.kawasaki.jp", "https://www.city.kawasaki.jp", true},
+		{"https://www.ck", "https://a.www.ck", true},
+
+		// Unicode and punycode forms of the same host
+		{"https://bücher.example", "https://www.xn--bcher-kva.example", true},
+		{"https://example.公司.cn", "https://www.example.xn--55qx5d.cn", true},
+
+		// IP literals and single-label hosts are their own site
+		{"https://127.0.0.1", "https://127.0.0.1:8080", true},
+		{"https://127.0.0.1", "https://127.0.0.2", false},
+		{"https://[::1]", "https://[::1]:8443", true},
+		{"https://localhost", "https://localhost:3000", true},
+		{"https://localhost", "https://a.localhost", false},
+
+		// Opaque and relative URLs are never same-site
+		{"data:text/plain,a", "data:text/plain,a", false},
+		{"/a", "/b", false},
+	}
+	for _, tt := range tests {
+		a, err := url.Parse(tt.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := url.Parse(tt.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := SameSite(a, b); got != tt.want {
+			t.Errorf("SameSite(%q, %q) = %v; want %v", tt.a, tt.b, got, tt.want)
+		}
+		if got := SameSite(b, a); got != tt.want {
+			t.Errorf("SameSite(%q, %q) = %v; want %v", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestSameOrigin(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"https://a.com/x", "https://a.com/y?q", true},
+
+		// Default ports compare equal to explicit ones
+		{"https://a.com", "https://a.com:443", true},
+		{"http://a.com", "http://a.com:80", true},
+		{"https://a.com", "https://a.com:8443", false},
+		{"http://a.com:443", "https://a.com", false},
+
+		// Schemes
+		{"http://a.com", "https://a.com", false},
+		{"HTTPS://a.com", "https://a.com", true},
+		{"wss://a.com", "https://a.com", true},
+		{"ws://a.com", "https://a.com", false},
+
+		// Hosts
+		{"https://A.COM", "https://a.com", true},
+		{"https://www.a.com", "https://a.com", false},
+		{"https://bücher.example", "https://xn--bcher-kva.example", true},
+
+		// IPv6 hosts
+		{"https://[::1]", "https://[::1]:443", true},
+		{"https://[::1]", "https://[0:0::1]", true},
+		{"https://[2001:DB8::1]", "https://[2001:db8::1]", true},
+		{"https://[::1]", "https://[::2]", false},
+		{"https://[::1]", "https://127.0.0.1", false},
+
+		// Opaque and relative URLs have no origin to share
+		{"data:text/plain,a", "data:text/plain,a", false},
+		{"about:blank", "about:blank", false},
+		{"file:///tmp/a", "file:///tmp/a", false},
+		{"/a", "/a", false},
+		{"//a.com/x", "//a.com/x", false},
+		{"/a", "https://a.com/a", false},
+	}
+	for _, tt := range tests {
+		a, err := url.Parse(tt.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := url.Parse(tt.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := SameOrigin(a, b); got != tt.want {
+			t.Errorf("SameOrigin(%q, %q) = %v; want %v", tt.a, tt.b, got, tt.want)
+		}
+		if got := SameOrigin(b, a); got != tt.want {
+			t.Errorf("SameOrigin(%q, %q) = %v; want %v", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package headers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"path"
+)
+
+// SniffLen is the number of leading bytes DetectContentType considers
+const SniffLen = 512
+
+// signature is a magic number at a fixed offset
+type signature struct {
+	offset int
+	magic  []byte
+	ct     ContentType
+}
+
+// Signatures checked before falling back to http.DetectContentType, covering
+// formats the standard sniffer does not know
+var sniffSignatures = []signature{
+	{0, []byte("wOF2"), "font/woff2"},
+	{0, []byte("wOFF"), "font/woff"},
+	{0, []byte("OTTO"), "font/otf"},
+	{0, []byte{0x00, 0x01, 0x00, 0x00, 0x00}, "font/ttf"},
+	{0, []byte{0x28, 0xB5, 0x2F, 0xFD}, "application/zstd"},
+	{0, []byte{0xFD, '7', 'z', 'X', 'Z', 0x00}, "application/x-xz"},
+	{0, []byte("BZh"), "application/x-bzip2"},
+	{0, []byte{'7', 'z', 0xBC, 0xAF, 0x27, 0x1C}, "application/x-7z-compressed"},
+	{0, []byte{0xFF, 0x0A}, "image/jxl"},
+	{0, []byte{0x00, 0x00, 0x00, 0x0C, 'J', 'X', 'L', ' ', 0x0D, 0x0A, 0x87, 0x0A}, "image/jxl"},
+	{0, []byte("SQLite format 3\x00"), "application/vnd.sqlite3"},
+	{0, []byte("PAR1"), "application/vnd.apache.parquet"},
+	{0, []byte("%!PS"), "application/postscript"},
+	{0, []byte("{\\rtf"), "application/rtf"},
+}
+
+// ISO base media file format brands (ftyp box at offset 4)
+var ftypBrands = map[string]ContentType{
+	"avif": "image/avif",
+	"avis": "image/avif-sequence",
+	"heic": "image/heic",
+	"heix": "image/heic",
+	"mif1": "image/heif",
+	"msf1": "image/heif",
+	"qt  ": ContentTypeVideoMOV,
+	"M4A ": "audio/mp4",
+	"3gp4": "video/3gpp",
+	"3gp5": "video/3gpp",
+}
+
+// DetectContentType determines the content type of data from its leading bytes,
+// extending http.DetectContentType with AVIF, HEIF, JPEG XL, WOFF2, TrueType,
+// zstd, xz, bzip2, 7z, SQLite and Parquet signatures, JSON and SVG documents.
+// Brotli streams have no signature and are reported as application/octet-stream.
+func DetectContentType(data []byte) ContentType {
+	if len(data) > SniffLen {
+		data = data[:SniffLen]
+	}
+
+	if len(data) >= 12 && bytes.Equal(data[4:8], []byte("ftyp")) {
+		if ct, ok := ftypBrands[string(data[8:12])]; ok {
+			return ct
+		}
+	}
+	for _, sig := range sniffSignatures {
+		if len(data) >= sig.offset+len(sig.magic) && bytes.Equal(data[sig.offset:sig.offset+len(sig.magic)], sig.magic) {
+			return sig.ct
+		}
+	}
+
+	ct := ContentType(http.DetectContentType(data))
+	switch ct.Essence() {
+	case ContentTypeTextPlain:
+		trimmed := bytes.TrimSpace(data)
+		if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+			return ContentTypeJSON
+		}
+	case ContentTypeTextXML:
+		if bytes.Contains(bytes.ToLower(data), []byte("<svg")) {
+			return ContentTypeImageSVG
+		}
+	}
+	return ct
+}
+
+// DetectFileContentType picks a content type the way browsers do for uploads:
+// by filename extension first, then by sniffing the leading bytes
+func DetectFileContentType(filename string, head []byte) ContentType {
+	if ext := path.Ext(filename); ext != "" {
+		if ct, ok := LookupExtension(ext); ok {
+			return ct
+		}
+	}
+	if len(head) == 0 {
+		return ContentTypeApplicationOctet
+	}
+	return DetectContentType(head)
+}
+
+// peekContentType detects the content type of a file from its extension or, when
+// that is unknown, from up to SniffLen bytes read from r. The returned reader
+// yields the full original stream.
+func peekContentType(filename string, r io.Reader) (ContentType, io.Reader, error) {
+	if ct, ok := LookupExtension(path.Ext(filename)); ok {
+		return ct, r, nil
+	}
+
+	head := make([]byte, SniffLen)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", nil, err
+	}
+	head = head[:n]
+	return DetectFileContentType(filename, head), io.MultiReader(bytes.NewReader(head), r), nil
+}
@@ -0,0 +1,442 @@
+package headers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Trace Context limits from the W3C specification
+const (
+	traceStateMaxMembers   = 32
+	traceStateMaxLength    = 512
+	traceStateMaxValue     = 256
+	traceStateLargeMember  = 128
+	traceParentLengthV0    = 55
+	traceParentVersionNone = 0xFF
+)
+
+// Trace Context errors
+var (
+	ErrInvalidTraceParent = errors.New("headers: invalid traceparent")
+	ErrInvalidTraceState  = errors.New("headers: invalid tracestate")
+)
+
+// TraceID identifies a whole trace
+type TraceID [16]byte
+
+// SpanID identifies one operation within a trace
+type SpanID [8]byte
+
+// TraceFlags holds the traceparent trace-flags field
+type TraceFlags byte
+
+// TraceFlagsSampled marks a trace the caller may have recorded
+const TraceFlagsSampled TraceFlags = 0x01
+
+// NewTraceID returns a random, valid trace ID
+func NewTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+// NewSpanID returns a random, valid span ID
+func NewSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+// IsValid reports whether the ID is not all zeros
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// IsValid reports whether the ID is not all zeros
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// TraceParent is a parsed traceparent header
+type TraceParent struct {
+	Version  byte
+	TraceID  TraceID
+	ParentID SpanID
+	Flags    TraceFlags
+}
+
+// NewTraceParent starts a new trace with random IDs
+func NewTraceParent(sampled bool) TraceParent {
+	p := TraceParent{TraceID: NewTraceID(), ParentID: NewSpanID()}
+	if sampled {
+		p.Flags = TraceFlagsSampled
+	}
+	return p
+}
+
+// Sampled reports whether the sampled flag is set
+func (p TraceParent) Sampled() bool {
+	return p.Flags&TraceFlagsSampled != 0
+}
+
+// WithSampled returns a copy with the sampled flag set or cleared
+func (p TraceParent) WithSampled(sampled bool) TraceParent {
+	if sampled {
+		p.Flags |= TraceFlagsSampled
+	} else {
+		p.Flags &^= TraceFlagsSampled
+	}
+	return p
+}
+
+// Child returns the traceparent for a new span in the same trace. It is
+// always version 00, so flags unknown to that version are dropped.
+func (p TraceParent) Child() TraceParent {
+	return TraceParent{
+		TraceID:  p.TraceID,
+		ParentID: NewSpanID(),
+		Flags:    p.Flags & TraceFlagsSampled,
+	}
+}
+
+// String formats the header in version 00 form
+func (p TraceParent) String() string {
+	return fmt.Sprintf("00-%s-%s-%02x", p.TraceID, p.ParentID, byte(p.Flags))
+}
+
+// ParseTraceParent parses a traceparent header. Versions above 00 are accepted
+// when their prefix follows the version 00 layout, as the specification requires.
+func ParseTraceParent(s string) (TraceParent, error) {
+	s = strings.TrimSpace(s)
+	invalid := fmt.Errorf("%w: %q", ErrInvalidTraceParent, s)
+	if len(s) < traceParentLengthV0 || s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return TraceParent{}, invalid
+	}
+
+	var p TraceParent
+	version, ok := decodeLowerHex(s[0:2])
+	if !ok || version[0] == traceParentVersionNone {
+		return TraceParent{}, invalid
+	}
+	p.Version = version[0]
+	if p.Version == 0 && len(s) != traceParentLengthV0 {
+		return TraceParent{}, invalid
+	}
+	if p.Version > 0 && len(s) > traceParentLengthV0 && s[traceParentLengthV0] != '-' {
+		return TraceParent{}, invalid
+	}
+
+	traceID, ok := decodeLowerHex(s[3:35])
+	if !ok {
+		return TraceParent{}, invalid
+	}
+	parentID, ok := decodeLowerHex(s[36:52])
+	if !ok {
+		return TraceParent{}, invalid
+	}
+	flags, ok := decodeLowerHex(s[53:55])
+	if !ok {
+		return TraceParent{}, invalid
+	}
+	copy(p.TraceID[:], traceID)
+	copy(p.ParentID[:], parentID)
+	p.Flags = TraceFlags(flags[0])
+	if !p.TraceID.IsValid() || !p.ParentID.IsValid() {
+		return TraceParent{}, invalid
+	}
+	return p, nil
+}
+
+// decodeLowerHex decodes lowercase hex, rejecting uppercase digits
+func decodeLowerHex(s string) ([]byte, bool) {
+	if strings.ToLower(s) != s {
+		return nil, false
+	}
+	b, err := hex.DecodeString(s)
+	return b, err == nil
+}
+
+// TraceState is an immutable, ordered tracestate list. The most recently
+// updated vendor entry comes first.
+type TraceState struct {
+	members []traceMember
+}
+
+// traceMember is one key=value list member
+type traceMember struct {
+	key, value string
+}
+
+// ParseTraceState parses and combines tracestate header values. Empty list
+// members are ignored; invalid members, duplicate keys or more than 32
+// members make the whole value invalid.
+func ParseTraceState(values ...string) (TraceState, error) {
+	var s TraceState
+	seen := make(map[string]bool)
+	for _, value := range values {
+		for member := range strings.SplitSeq(value, ",") {
+			member = strings.Trim(member, " \t")
+			if member == "" {
+				continue
+			}
+			key, val, ok := strings.Cut(member, "=")
+			if !ok || !validTraceStateKey(key) || !validTraceStateValue(val) || seen[key] {
+				return TraceState{}, fmt.Errorf("%w: %q", ErrInvalidTraceState, member)
+			}
+			seen[key] = true
+			s.members = append(s.members, traceMember{key: key, value: val})
+		}
+	}
+	if len(s.members) > traceStateMaxMembers {
+		return TraceState{}, fmt.Errorf("%w: more than %d members", ErrInvalidTraceState, traceStateMaxMembers)
+	}
+	return s, nil
+}
+
+// Get returns the value for a vendor key
+func (s TraceState) Get(key string) (string, bool) {
+	for _, m := range s.members {
+		if m.key == key {
+			return m.value, true
+		}
+	}
+	return "", false
+}
+
+// Len returns the number of list members
+func (s TraceState) Len() int {
+	return len(s.members)
+}
+
+// Set returns a copy with key set to value and moved to the front, as vendors
+// must do when updating their entry. When the list would exceed 32 members,
+// the rightmost ones are dropped.
+func (s TraceState) Set(key, value string) (TraceState, error) {
+	if !validTraceStateKey(key) || !validTraceStateValue(value) {
+		return s, fmt.Errorf("%w: %q", ErrInvalidTraceState, key+"="+value)
+	}
+	members := make([]traceMember, 0, len(s.members)+1)
+	members = append(members, traceMember{key: key, value: value})
+	for _, m := range s.members {
+		if m.key != key {
+			members = append(members, m)
+		}
+	}
+	if len(members) > traceStateMaxMembers {
+		members = members[:traceStateMaxMembers]
+	}
+	return TraceState{members: members}, nil
+}
+
+// Delete returns a copy without key
+func (s TraceState) Delete(key string) TraceState {
+	members := make([]traceMember, 0, len(s.members))
+	for _, m := range s.members {
+		if m.key != key {
+			members = append(members, m)
+		}
+	}
+	return TraceState{members: members}
+}
+
+// String formats the list, truncating it to 512 characters for propagation:
+// members longer than 128 characters are dropped first, then members from the
+// right
+func (s TraceState) String() string {
+	members := s.members
+	length := func(ms []traceMember) int {
+		n := 0
+		for i, m := range ms {
+			if i > 0 {
+				n++
+			}
+			n += len(m.key) + 1 + len(m.value)
+		}
+		return n
+	}
+	if length(members) > traceStateMaxLength {
+		kept := make([]traceMember, 0, len(members))
+		for _, m := range members {
+			if len(m.key)+1+len(m.value) <= traceStateLargeMember {
+				kept = append(kept, m)
+			}
+		}
+		for length(kept) > traceStateMaxLength {
+			kept = kept[:len(kept)-1]
+		}
+		members = kept
+	}
+
+	parts := make([]string, len(members))
+	for i, m := range members {
+		parts[i] = m.key + "=" + m.value
+	}
+	return strings.Join(parts, ",")
+}
+
+// validTraceStateKey checks a simple key or a multi-tenant tenant@system key
+func validTraceStateKey(key string) bool {
+	tenant, system, multi := strings.Cut(key, "@")
+	if !multi {
+		return len(key) <= 256 && isTraceKeyPart(key, true)
+	}
+	return len(tenant) <= 241 && isTraceKeyPart(tenant, false) &&
+		len(system) <= 14 && isTraceKeyPart(system, true)
+}
+
+// isTraceKeyPart checks lcalpha or digit followed by lcalpha, digits and _-*/
+func isTraceKeyPart(s string, alphaFirst bool) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z':
+		case c >= '0' && c <= '9':
+			if i == 0 && alphaFirst {
+				return false
+			}
+		case i > 0 && (c == '_' || c == '-' || c == '*' || c == '/'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// validTraceStateValue checks 1 to 256 printable ASCII characters other than
+// comma and equals, not ending in a space
+func validTraceStateValue(value string) bool {
+	if value == "" || len(value) > traceStateMaxValue || strings.HasSuffix(value, " ") {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		if c := value[i]; c < 0x20 || c > 0x7E || c == ',' || c == '=' {
+			return false
+		}
+	}
+	return true
+}
+
+// TraceContext is the trace position of the current operation: its own span ID
+// in TraceParent.ParentID, as it will be sent to callees, plus vendor state
+type TraceContext struct {
+	TraceParent
+	TraceState TraceState
+}
+
+// traceContextKey is the context key under which the TraceContext is stored
+type traceContextKey struct{}
+
+// WithTraceContext returns a context carrying the trace context
+func WithTraceContext(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, tc)
+}
+
+// TraceContextFromContext returns the trace context stored in ctx, if any
+func TraceContextFromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(traceContextKey{}).(TraceContext)
+	return tc, ok
+}
+
+// ExtractTraceContext reads traceparent and tracestate from a header. An
+// invalid tracestate is dropped without failing, since the traceparent alone
+// is enough to continue the trace.
+func ExtractTraceContext(header http.Header) (TraceContext, error) {
+	values := header.Values(HeaderTraceParent)
+	if len(values) != 1 {
+		return TraceContext{}, fmt.Errorf("%w: %d headers", ErrInvalidTraceParent, len(values))
+	}
+	parent, err := ParseTraceParent(values[0])
+	if err != nil {
+		return TraceContext{}, err
+	}
+	state, _ := ParseTraceState(header.Values(HeaderTraceState)...)
+	return TraceContext{TraceParent: parent, TraceState: state}, nil
+}
+
+// Inject writes traceparent and tracestate into a header
+func (tc TraceContext) Inject(header http.Header) {
+	header.Set(HeaderTraceParent, tc.TraceParent.String())
+	if state := tc.TraceState.String(); state != "" {
+		header.Set(HeaderTraceState, state)
+	} else {
+		header.Del(HeaderTraceState)
+	}
+}
+
+// TraceTransport is an http.RoundTripper that propagates the trace context in
+// the request context, or starts a new trace when there is none
+type TraceTransport struct {
+	Transport http.RoundTripper // Underlying transport, http.DefaultTransport when nil
+	Sampled   bool              // Sampled flag for traces started here
+}
+
+// NewTraceTransport creates a TraceTransport over next
+func NewTraceTransport(next http.RoundTripper) *TraceTransport {
+	return &TraceTransport{Transport: next}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *TraceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	tc, ok := TraceContextFromContext(req.Context())
+	if !ok {
+		tc = TraceContext{TraceParent: NewTraceParent(t.Sampled)}
+	}
+	req = req.Clone(req.Context())
+	tc.Inject(req.Header)
+	return next.RoundTrip(req)
+}
+
+// TracePropagator is HTTP middleware that continues the trace of an incoming
+// request, or starts one, and stores a TraceContext for the server's own span
+// in the request context
+type TracePropagator struct {
+	// Sampled sets the sampled flag for traces started here
+	Sampled bool
+	// IgnoreIncoming starts a new trace for every request, for edges facing
+	// untrusted clients
+	IgnoreIncoming bool
+}
+
+// TraceHandler wraps next with a TracePropagator using default settings
+func TraceHandler(next http.Handler) http.Handler {
+	return (&TracePropagator{}).Handler(next)
+}
+
+// Handler returns next wrapped with trace propagation
+func (p *TracePropagator) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var tc TraceContext
+		incoming, err := ExtractTraceContext(r.Header)
+		if err == nil && !p.IgnoreIncoming {
+			tc = TraceContext{TraceParent: incoming.Child(), TraceState: incoming.TraceState}
+		} else {
+			tc = TraceContext{TraceParent: NewTraceParent(p.Sampled)}
+		}
+		next.ServeHTTP(w, r.WithContext(WithTraceContext(r.Context(), tc)))
+	})
+}
@@ -38,6 +38,9 @@ type Upgrade string
 // Range represents the Range header value
 type Range string
 
+// ContentRange represents the Content-Range header value
+type ContentRange string
+
 // ContentDisposition represents the Content-Disposition header value
 type ContentDisposition string
 
@@ -47,6 +50,9 @@ type TransferEncoding string
 // TE represents the TE (Transfer Encoding) header value
 type TE string
 
+// Priority represents the Priority header value (RFC 9218)
+type Priority string
+
 // Security-related header types
 
 // DNT represents the DNT (Do Not Track) header value
@@ -92,6 +98,9 @@ type SecFetchSite string
 // SecFetchUser represents the Sec-Fetch-User header value
 type SecFetchUser string
 
+// ReferrerPolicy represents a Referrer-Policy header value
+type ReferrerPolicy string
+
 // Sec-CH-* header types (Client Hints)
 
 // SecCHUAMobile represents the Sec-CH-UA-Mobile header value
@@ -105,3 +114,12 @@ type SecCHPrefersColorScheme string
 
 // SecCHPrefersReducedMotion represents the Sec-CH-Prefers-Reduced-Motion header value
 type SecCHPrefersReducedMotion string
+
+// Browser emulation types
+
+// Profile identifies a browser whose request behavior is emulated
+type Profile string
+
+// RequestKind identifies the kind of request a browser makes, which determines
+// its destination and mode in the Fetch standard
+type RequestKind string